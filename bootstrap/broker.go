@@ -0,0 +1,106 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/succko/hera/broker"
+	"github.com/succko/hera/config"
+	"github.com/succko/hera/global"
+)
+
+// InitializeBroker 按配置创建消息代理，并订阅代码与配置中声明的所有消费者
+func InitializeBroker() (broker.Broker, error) {
+	specs, err := consumerSpecs()
+	if err != nil {
+		return nil, err
+	}
+	cfg := global.Config().Rokcetmq
+	var b broker.Broker
+	switch cfg.Broker {
+	case "", config.BrokerRocketmq:
+		if b, err = newRocketmqBroker(); err != nil {
+			return nil, err
+		}
+	case config.BrokerMemory:
+		b = broker.NewMemory(broker.MemoryOptions{MaxRetries: cfg.MaxRetries, DlqSuffix: cfg.DlqSuffix})
+	default:
+		return nil, fmt.Errorf("unknown rocketmq broker %q", cfg.Broker)
+	}
+	for _, spec := range specs {
+		if err := b.Subscribe(spec); err != nil {
+			_ = b.Close()
+			return nil, fmt.Errorf("consumer %s(%s): %w", spec.Topic, spec.Group, err)
+		}
+	}
+	return b, nil
+}
+
+// rocketmqBroker 基于 RocketMQ 的消息代理，生产者与消费者保存在 global.App 中
+type rocketmqBroker struct {
+	mu sync.Mutex
+}
+
+// 启动生产者，消费失败的消息需要生产者投递到死信主题，因此生产者先于消费者启动
+func newRocketmqBroker() (*rocketmqBroker, error) {
+	global.App.RocketMqProducer = InitializeRocketMqProducer()
+	if global.App.RocketMqProducer == nil {
+		return nil, errors.New("rocketmq producer initialize failed")
+	}
+	tp, err := InitializeRocketMqTransactionProducer()
+	if err != nil {
+		_ = global.App.RocketMqProducer.Shutdown()
+		global.App.RocketMqProducer = nil
+		return nil, err
+	}
+	global.App.RocketMqTransactionProducer = tp
+	return &rocketmqBroker{}, nil
+}
+
+func (b *rocketmqBroker) Publish(ctx context.Context, msg *primitive.Message) error {
+	if global.App.RocketMqProducer == nil {
+		return broker.ErrClosed
+	}
+	_, err := global.App.RocketMqProducer.SendSync(ctx, msg)
+	return err
+}
+
+func (b *rocketmqBroker) Subscribe(spec config.Consumer) error {
+	c, err := initializeRocketMqConsumer(spec)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	global.App.RocketMqConsumers = append(global.App.RocketMqConsumers, c)
+	b.mu.Unlock()
+	return nil
+}
+
+// Close 先停止消费，再关闭生产者
+func (b *rocketmqBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var errs []error
+	for _, consumer := range global.App.RocketMqConsumers {
+		if err := consumer.Shutdown(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	global.App.RocketMqConsumers = nil
+	if global.App.RocketMqTransactionProducer != nil {
+		if err := global.App.RocketMqTransactionProducer.Shutdown(); err != nil {
+			errs = append(errs, err)
+		}
+		global.App.RocketMqTransactionProducer = nil
+	}
+	if global.App.RocketMqProducer != nil {
+		if err := global.App.RocketMqProducer.Shutdown(); err != nil {
+			errs = append(errs, err)
+		}
+		global.App.RocketMqProducer = nil
+	}
+	return errors.Join(errs...)
+}
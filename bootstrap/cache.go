@@ -0,0 +1,26 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/succko/hera/cache"
+	"github.com/succko/hera/config"
+)
+
+// 基于默认Redis实例创建缓存
+func newCache(rdb redis.UniversalClient, cfg config.Cache) *cache.Cache {
+	// 未配置 jitter 时使用默认值，配置为 0 时不浮动
+	jitter := -1.0
+	if cfg.Jitter != nil {
+		jitter = *cfg.Jitter
+	}
+	return cache.New(rdb, cache.Options{
+		Prefix:      cfg.Prefix,
+		TTL:         time.Duration(cfg.Ttl) * time.Second,
+		Jitter:      jitter,
+		NegativeTTL: time.Duration(cfg.NegativeTtl) * time.Second,
+		LocalSize:   cfg.LocalSize,
+		LocalTTL:    time.Duration(cfg.LocalTtl) * time.Second,
+	})
+}
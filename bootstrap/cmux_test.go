@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/succko/hera/config"
+	"github.com/succko/hera/global"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// gRPC、WebSocket 与 HTTP 共用一个端口
+func TestRunCMux(t *testing.T) {
+	port := freePort(t)
+	global.SwapConfig(&config.Configuration{
+		App: config.App{AppName: "hera-test", Port: port},
+		Ws:  config.Ws{Auth: config.WsAuth{Secret: "test"}},
+	})
+	lg = zap.NewNop()
+	global.App.Modules = &config.AllModules{Grpc: true, Ws: true, Http: true}
+	global.App.RunConfig.Grpc = func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, grpchealth.NewServer())
+	}
+	if err := RunCMux(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		Shutdown.Trigger()
+		_ = Shutdown.Wait()
+	}()
+	addr := "127.0.0.1:" + port
+
+	t.Run("http", func(t *testing.T) {
+		resp, err := http.Get("http://" + addr + "/ping")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || string(body) != "pong" {
+			t.Fatalf("GET /ping = %d %q", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("health status = %v", resp.GetStatus())
+		}
+	})
+
+	t.Run("ws", func(t *testing.T) {
+		conn, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+wsPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("upgrade status = %d", resp.StatusCode)
+		}
+	})
+}
+
+func freePort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
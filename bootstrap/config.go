@@ -1,52 +1,281 @@
 package bootstrap
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/fsnotify/fsnotify"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"github.com/succko/hera/config"
 	"github.com/succko/hera/global"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// 主配置在 Nacos 中的最新内容，热更新时叠加在配置文件之上
+	nacosContent   string
+	nacosContentMu sync.Mutex
+
+	// 当前配置中每个键的来源
+	configSources   map[string]string
+	configSourcesMu sync.Mutex
+
+	// 是否校验热更新的配置
+	validating atomic.Bool
 )
 
 // InitializeConfig 初始化配置
 func InitializeConfig() (*viper.Viper, error) {
 	// 设置配置文件路径
-	config := "config.yaml"
+	path := "config.yaml"
 	// 生产环境可以通过设置环境变量来改变配置文件路径
 	if configEnv := os.Getenv("VIPER_CONFIG"); configEnv != "" {
-		config = configEnv
+		path = configEnv
 	}
 
 	// 初始化 viper
 	v := viper.New()
-	v.SetConfigFile(config)
+	v.SetConfigFile(path)
 	v.SetConfigType("yaml")
 	if err := v.ReadInConfig(); err != nil {
 		zap.L().Error(fmt.Sprintf("read config failed: %s", err))
 		return nil, err
 	}
+	global.App.ConfigViper = v
+
+	// 将配置赋值给全局变量
+	c, sources, err := buildConfig()
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("unmarshal config failed: %s", err))
+		return nil, err
+	}
+	swapConfig(c, sources)
 
 	// 监听配置文件
 	v.WatchConfig()
 	v.OnConfigChange(func(in fsnotify.Event) {
 		zap.L().Info(fmt.Sprintf("config file changed: %s", in.Name))
 		// 重载配置
-		if err := v.Unmarshal(&global.App.Config); err != nil {
+		if err := ReloadConfig(); err != nil {
 			zap.L().Error(fmt.Sprintf("config file error: %s", err))
 		}
 	})
 
-	// 将配置赋值给全局变量
-	if err := v.Unmarshal(&global.App.Config); err != nil {
-		zap.L().Error(fmt.Sprintf("unmarshal config failed: %s", err))
+	if c.App.Env == gin.ReleaseMode {
+		gin.SetMode(c.App.Env)
+	}
+
+	return v, nil
+}
+
+// ReloadConfig 按优先级重新合并各配置来源，校验通过后原子替换当前配置
+func ReloadConfig() error {
+	c, sources, err := buildConfig()
+	if err != nil {
+		return err
+	}
+	// 启动时的配置尚未合并完整，校验在 ValidateConfig 之后才开始生效
+	if validating.Load() {
+		if err := c.Validate(global.App.Modules); err != nil {
+			return err
+		}
+	}
+	changes := swapConfig(c, sources)
+	for _, change := range changes {
+		zap.L().Info("config changed", zap.String("path", change.Path))
+	}
+	return nil
+}
+
+// ValidateConfig 按启用的模块校验合并完成的配置，通过后热更新的配置也需通过校验才会生效
+func ValidateConfig() error {
+	if err := global.Config().Validate(global.App.Modules); err != nil {
+		return err
+	}
+	validating.Store(true)
+	return nil
+}
+
+// 一个配置来源展开后的键值
+type layer struct {
+	source string
+	values map[string]any
+}
+
+// 构造一份新的配置，按 config.Sources 的顺序逐层覆盖：默认值 < 配置文件 < 环境覆盖文件 < 环境变量 < Nacos < 命令行参数
+func buildConfig() (*config.Configuration, map[string]string, error) {
+	file := config.Flatten(global.App.ConfigViper.AllSettings())
+	layers := []layer{
+		{config.SourceDefault, config.Defaults()},
+		{config.SourceFile, file},
+	}
+	overlay, err := overlayLayer(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	layers = append(layers, layer{config.SourceOverlay, overlay}, layer{config.SourceEnv, envLayer()})
+	nacos, err := nacosLayer()
+	if err != nil {
+		return nil, nil, err
+	}
+	layers = append(layers, layer{config.SourceNacos, nacos}, layer{config.SourceFlag, flagLayer()})
+
+	merged := viper.New()
+	sources := make(map[string]string)
+	for _, l := range layers {
+		for k, v := range l.values {
+			// 空值视为未配置，不覆盖低优先级的来源
+			if v == nil {
+				continue
+			}
+			merged.Set(k, v)
+			sources[k] = l.source
+		}
+	}
+	c := new(config.Configuration)
+	if err := merged.Unmarshal(c); err != nil {
+		return nil, nil, err
+	}
+	if err := config.ResolveSecrets(c); err != nil {
+		return nil, nil, err
+	}
+	return c, sources, nil
+}
+
+// 与环境对应的覆盖文件，config.yaml 在 release 环境下对应 config.release.yaml，文件不存在时忽略
+func overlayLayer(file map[string]any) (map[string]any, error) {
+	env, _ := config.Defaults()["app.env"].(string)
+	if v, ok := file["app.env"].(string); ok && v != "" {
+		env = v
+	}
+	if v := os.Getenv(config.EnvName("app.env")); v != "" {
+		env = v
+	}
+	if v, ok := flagLayer()["app.env"].(string); ok {
+		env = v
+	}
+	path := global.App.ConfigViper.ConfigFileUsed()
+	ext := filepath.Ext(path)
+	path = strings.TrimSuffix(path, ext) + "." + env + ext
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
 		return nil, err
 	}
+	return config.Flatten(v.AllSettings()), nil
+}
 
-	if global.App.Config.App.Env == gin.ReleaseMode {
-		gin.SetMode(global.App.Config.App.Env)
+// 环境变量，键路径 database.host 对应 HERA_DATABASE_HOST，字符串切片以逗号分隔
+func envLayer() map[string]any {
+	values := make(map[string]any)
+	for path, v := range config.Values(new(config.Configuration)) {
+		s, ok := os.LookupEnv(config.EnvName(path))
+		if !ok {
+			continue
+		}
+		switch t := reflect.TypeOf(v); t.Kind() {
+		case reflect.Slice:
+			if t.Elem().Kind() == reflect.String {
+				values[path] = strings.Split(s, ",")
+			}
+		case reflect.Map, reflect.Struct:
+		default:
+			values[path] = s
+		}
 	}
+	return values
+}
 
-	return v, nil
+// Nacos 中主配置的最新内容
+func nacosLayer() (map[string]any, error) {
+	nacosContentMu.Lock()
+	content := nacosContent
+	nacosContentMu.Unlock()
+	if content == "" {
+		return nil, nil
+	}
+	m := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(content), &m); err != nil {
+		return nil, err
+	}
+	return config.Flatten(m), nil
+}
+
+// 替换当前配置并记录每个键的来源
+func swapConfig(c *config.Configuration, sources map[string]string) []config.Change {
+	configSourcesMu.Lock()
+	configSources = sources
+	configSourcesMu.Unlock()
+	return global.SwapConfig(c)
+}
+
+// PrintConfig 输出当前生效的配置及每个值的来源，config.Secret 类型的字段以 ****** 代替
+func PrintConfig(w io.Writer) error {
+	configSourcesMu.Lock()
+	sources := configSources
+	configSourcesMu.Unlock()
+	values := config.Values(global.Config())
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		b, err := json.Marshal(values[path])
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s = %s (%s)\n", path, b, source(sources, path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 键路径的来源，映射等展开后的键取优先级最高的来源，均未配置时为 -
+func source(sources map[string]string, path string) string {
+	rank := -1
+	for k, s := range sources {
+		if k != path && !strings.HasPrefix(k, path+".") {
+			continue
+		}
+		for i, name := range config.Sources {
+			if name == s && i > rank {
+				rank = i
+			}
+		}
+	}
+	if rank < 0 {
+		return "-"
+	}
+	return config.Sources[rank]
+}
+
+// 应用 Nacos 中主配置的新内容
+func applyNacosConfig(content string) error {
+	nacosContentMu.Lock()
+	prev := nacosContent
+	nacosContent = content
+	nacosContentMu.Unlock()
+	if err := ReloadConfig(); err != nil {
+		// 新内容无效时保留上一次的内容，避免之后的配置文件变更也无法生效
+		nacosContentMu.Lock()
+		nacosContent = prev
+		nacosContentMu.Unlock()
+		return err
+	}
+	return nil
 }
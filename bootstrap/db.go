@@ -1,113 +1,170 @@
 package bootstrap
 
 import (
+	"database/sql"
+	"fmt"
+	"github.com/glebarez/sqlite"
+	"github.com/succko/hera/config"
 	"github.com/succko/hera/global"
 	"go.uber.org/zap"
-	"gopkg.in/natefinch/lumberjack.v2"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	"io"
-	"log"
-	"os"
+	"gorm.io/plugin/dbresolver"
 	"strconv"
 	"time"
 )
 
-func InitializeDB() *gorm.DB {
-	// 根据驱动配置进行初始化
-	switch global.App.Config.Database.Driver {
-	case "mysql":
-		return initMySqlGorm()
-	default:
-		return initMySqlGorm()
+func InitializeDB() (*gorm.DB, error) {
+	db, err := openDB("default", global.Config().Database)
+	if err != nil {
+		return nil, fmt.Errorf("database connect failed: %w", err)
 	}
+	return db, nil
 }
 
-// 初始化 mysql gorm.DB
-func initMySqlGorm() *gorm.DB {
-	dbConfig := global.App.Config.Database
-
-	if dbConfig.Database == "" {
-		return nil
-	}
-	dsn := dbConfig.UserName + ":" + dbConfig.Password + "@tcp(" + dbConfig.Host + ":" + strconv.Itoa(dbConfig.Port) + ")/" +
-		dbConfig.Database + "?charset=" + dbConfig.Charset + "&parseTime=True&loc=Local"
-	mysqlConfig := mysql.Config{
-		DSN:                       dsn,   // DSN data source name
-		DefaultStringSize:         191,   // string 类型字段的默认长度
-		DisableDatetimePrecision:  true,  // 禁用 datetime 精度，MySQL 5.6 之前的数据库不支持
-		DontSupportRenameIndex:    true,  // 重命名索引时采用删除并新建的方式，MySQL 5.7 之前的数据库和 MariaDB 不支持重命名索引
-		DontSupportRenameColumn:   true,  // 用 `change` 重命名列，MySQL 8 之前的数据库和 MariaDB 不支持重命名列
-		SkipInitializeWithVersion: false, // 根据版本自动配置
-	}
-	if db, err := gorm.Open(mysql.New(mysqlConfig), &gorm.Config{
-		DisableForeignKeyConstraintWhenMigrating: true,            // 禁用自动创建外键约束
-		Logger:                                   getGormLogger(), // 使用自定义 Logger
-	}); err != nil {
-		zap.L().Error("mysql connect failed, err:", zap.Any("err", err))
-		return nil
-	} else {
-		sqlDB, _ := db.DB()
-		sqlDB.SetMaxIdleConns(dbConfig.MaxIdleConns)
-		sqlDB.SetMaxOpenConns(dbConfig.MaxOpenConns)
-		//initMySqlTables(db)
-		return db
+// InitializeDBs 初始化配置文件 databases 中声明的具名数据库连接，任一连接失败时关闭已建立的连接
+func InitializeDBs() (map[string]*gorm.DB, error) {
+	dbs := make(map[string]*gorm.DB, len(global.Config().Databases))
+	for name, cfg := range global.Config().Databases {
+		db, err := openDB(name, cfg)
+		if err != nil {
+			closeDBs(dbs)
+			return nil, fmt.Errorf("database %s: %w", name, err)
+		}
+		dbs[name] = db
 	}
+	return dbs, nil
 }
 
-// 数据库表初始化
-func initMySqlTables(db *gorm.DB) {
-	err := db.AutoMigrate(
-	//models.MemberUser{},
-	)
-	if err != nil {
-		zap.L().Error("migrate table failed", zap.Any("err", err))
-		os.Exit(0)
+func closeDBs(dbs map[string]*gorm.DB) {
+	for _, db := range dbs {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
 	}
 }
 
-func getGormLogger() logger.Interface {
-	var logMode logger.LogLevel
-
-	switch global.App.Config.Database.LogMode {
-	case "silent":
-		logMode = logger.Silent
-	case "error":
-		logMode = logger.Error
-	case "warn":
-		logMode = logger.Warn
-	case "info":
-		logMode = logger.Info
-	default:
-		logMode = logger.Info
+// 根据驱动配置打开连接，配置了只读副本时通过 dbresolver 实现读写分离
+func openDB(name string, cfg config.Database) (*gorm.DB, error) {
+	dialector, err := newDialector(cfg.Driver, cfg.Dsn.Value(), cfg)
+	if err != nil {
+		return nil, err
 	}
-
-	return logger.New(getGormLogWriter(), logger.Config{
-		SlowThreshold:             200 * time.Millisecond,                          // 慢 SQL 阈值
-		LogLevel:                  logMode,                                         // 日志级别
-		IgnoreRecordNotFoundError: false,                                           // 忽略ErrRecordNotFound（记录未找到）错误
-		Colorful:                  !global.App.Config.Database.EnableFileLogWriter, // 禁用彩色打印
+	db, err := gorm.Open(dialector, &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,                     // 禁用自动创建外键约束
+		Logger:                                   getGormLogger(name, cfg), // 使用自定义 Logger
 	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	setPool(sqlDB, cfg)
+
+	if len(cfg.Replicas) > 0 {
+		replicas := make([]gorm.Dialector, 0, len(cfg.Replicas))
+		for _, r := range cfg.Replicas {
+			// 副本未配置的参数与主库相同
+			replica := cfg
+			replica.Host = r.Host
+			if r.Port != 0 {
+				replica.Port = r.Port
+			}
+			if r.UserName != "" {
+				replica.UserName = r.UserName
+			}
+			if r.Password != "" {
+				replica.Password = r.Password
+			}
+			d, err := newDialector(cfg.Driver, r.Dsn.Value(), replica)
+			if err != nil {
+				_ = sqlDB.Close()
+				return nil, err
+			}
+			replicas = append(replicas, d)
+		}
+		resolver := dbresolver.Register(dbresolver.Config{
+			Replicas: replicas,
+			Policy:   dbresolver.RandomPolicy{},
+		}).
+			SetMaxIdleConns(cfg.MaxIdleConns).
+			SetMaxOpenConns(cfg.MaxOpenConns).
+			SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second).
+			SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Second)
+		if err := db.Use(resolver); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+	if global.Config().Metrics.Enable {
+		if err := db.Use(&metricsPlugin{name: name}); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+	return db, nil
 }
 
-// 自定义 gorm Writer
-func getGormLogWriter() logger.Writer {
-	var writer io.Writer
+// 连接池配置
+func setPool(sqlDB *sql.DB, cfg config.Database) {
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Second)
+}
 
-	// 是否启用日志文件
-	if global.App.Config.Database.EnableFileLogWriter {
-		// 自定义 Writer
-		writer = &lumberjack.Logger{
-			Filename:   global.App.Config.Log.RootDir + "/" + global.App.Config.Database.LogFilename,
-			MaxSize:    global.App.Config.Log.MaxSize,
-			MaxBackups: global.App.Config.Log.MaxBackups,
-			MaxAge:     global.App.Config.Log.MaxAge,
-			Compress:   global.App.Config.Log.Compress,
+// 按驱动创建 gorm 方言，dsn 不为空时直接使用
+func newDialector(driver, dsn string, cfg config.Database) (gorm.Dialector, error) {
+	switch driver {
+	case "", config.DriverMysql:
+		if dsn == "" {
+			dsn = cfg.UserName + ":" + cfg.Password.Value() + "@tcp(" + cfg.Host + ":" + strconv.Itoa(cfg.Port) + ")/" +
+				cfg.Database + "?charset=" + cfg.Charset + "&parseTime=True&loc=Local"
 		}
-	} else {
-		// 默认 Writer
-		writer = os.Stdout
+		return mysql.New(mysql.Config{
+			DSN:                       dsn,   // DSN data source name
+			DefaultStringSize:         191,   // string 类型字段的默认长度
+			DisableDatetimePrecision:  true,  // 禁用 datetime 精度，MySQL 5.6 之前的数据库不支持
+			DontSupportRenameIndex:    true,  // 重命名索引时采用删除并新建的方式，MySQL 5.7 之前的数据库和 MariaDB 不支持重命名索引
+			DontSupportRenameColumn:   true,  // 用 `change` 重命名列，MySQL 8 之前的数据库和 MariaDB 不支持重命名列
+			SkipInitializeWithVersion: false, // 根据版本自动配置
+		}), nil
+	case config.DriverPostgres:
+		if dsn == "" {
+			dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable TimeZone=Local",
+				cfg.Host, cfg.Port, cfg.UserName, cfg.Password.Value(), cfg.Database)
+		}
+		return postgres.Open(dsn), nil
+	case config.DriverSqlite:
+		if dsn == "" {
+			dsn = cfg.Database
+		}
+		return sqlite.Open(dsn), nil
 	}
-	return log.New(writer, "\r\n", log.LstdFlags)
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// 配置中的连接池大小变更时立即生效
+func watchDB() {
+	global.WatchConfig(func(old, new *config.Configuration, changes []config.Change) {
+		resize := func(path string, db *gorm.DB, cfg config.Database) {
+			pool := []string{path + ".max_idle_conns", path + ".max_open_conns", path + ".conn_max_lifetime", path + ".conn_max_idle_time"}
+			if db == nil || !config.Changed(changes, pool...) {
+				return
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				return
+			}
+			setPool(sqlDB, cfg)
+			zap.L().Info("database pool changed", zap.String("database", path), zap.Int("maxIdleConns", cfg.MaxIdleConns), zap.Int("maxOpenConns", cfg.MaxOpenConns))
+		}
+		resize("database", global.App.DB, new.Database)
+		for name, cfg := range new.Databases {
+			resize("databases."+name, global.App.DBs[name], cfg)
+		}
+	})
 }
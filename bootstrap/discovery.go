@@ -0,0 +1,156 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/succko/hera/discovery"
+	"github.com/succko/hera/global"
+	"go.uber.org/zap"
+)
+
+var (
+	namingOnce sync.Once
+	namingErr  error
+
+	// 各协议实际监听的端口，注册服务实例时写入元数据
+	servingPorts   = make(map[string]string)
+	servingPortsMu sync.Mutex
+)
+
+// NamingClient Nacos 服务发现客户端，首次使用时创建
+func NamingClient() (naming_client.INamingClient, error) {
+	namingOnce.Do(func() {
+		clientConfig, serverConfigs := nacosClientConfig()
+		global.App.Naming, namingErr = clients.NewNamingClient(vo.NacosClientParam{
+			ClientConfig:  &clientConfig,
+			ServerConfigs: serverConfigs,
+		})
+		if namingErr != nil {
+			namingErr = fmt.Errorf("nacos naming client: %w", namingErr)
+		}
+	})
+	return global.App.Naming, namingErr
+}
+
+// InitializeDiscovery 注册 nacos:///{service} 地址解析器，gRPC 客户端可按服务名调用其他服务
+func InitializeDiscovery() {
+	discovery.Register(NamingClient)
+}
+
+// 记录协议监听的端口
+func servingPort(proto string, l net.Listener) {
+	addr, ok := l.Addr().(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	servingPortsMu.Lock()
+	servingPorts[proto] = strconv.Itoa(addr.Port)
+	servingPortsMu.Unlock()
+}
+
+// RegisterInstance 服务启动后注册为 Nacos 服务实例，关闭时首先注销，使调用方不再选中本实例
+func RegisterInstance() error {
+	cfg := global.Config().Nacos.Discovery
+	if !global.App.Modules.Nacos || !cfg.Register {
+		return nil
+	}
+	client, err := NamingClient()
+	if err != nil {
+		return err
+	}
+
+	servingPortsMu.Lock()
+	// 未单独监听时 WebSocket 通过 HTTP 路由提供
+	if global.App.Modules.Ws && servingPorts["ws"] == "" {
+		servingPorts["ws"] = servingPorts["http"]
+	}
+	metadata := map[string]string{"env": global.Config().App.Env}
+	for proto, key := range map[string]string{"http": discovery.MetaHttpPort, "grpc": discovery.MetaGrpcPort, "ws": discovery.MetaWsPort} {
+		if port := servingPorts[proto]; port != "" {
+			metadata[key] = port
+		}
+	}
+	servingPortsMu.Unlock()
+	for k, v := range cfg.Metadata {
+		metadata[k] = v
+	}
+	// 实例端口优先使用 HTTP 端口
+	var port uint64
+	for _, key := range []string{discovery.MetaHttpPort, discovery.MetaGrpcPort, discovery.MetaWsPort} {
+		if p, err := strconv.ParseUint(metadata[key], 10, 64); err == nil {
+			port = p
+			break
+		}
+	}
+	if port == 0 {
+		return errors.New("nacos register: no serving port")
+	}
+	ip := cfg.Ip
+	if ip == "" {
+		if ip, err = localIp(); err != nil {
+			return err
+		}
+	}
+	service := cfg.Service
+	if service == "" {
+		service = global.Config().App.AppName
+	}
+	weight := cfg.Weight
+	if weight == 0 {
+		weight = 1
+	}
+
+	if _, err := client.RegisterInstance(vo.RegisterInstanceParam{
+		Ip:          ip,
+		Port:        port,
+		Weight:      weight,
+		Enable:      true,
+		Healthy:     true,
+		Metadata:    metadata,
+		ClusterName: cfg.Cluster,
+		ServiceName: service,
+		GroupName:   cfg.Group,
+		Ephemeral:   true,
+	}); err != nil {
+		return fmt.Errorf("nacos register %s: %w", service, err)
+	}
+	zap.L().Info("nacos instance registered", zap.String("service", service), zap.String("ip", ip), zap.Uint64("port", port))
+
+	Shutdown.Register(StageListener, "nacos", func(ctx context.Context) error {
+		_, err := client.DeregisterInstance(vo.DeregisterInstanceParam{
+			Ip:          ip,
+			Port:        port,
+			Cluster:     cfg.Cluster,
+			ServiceName: service,
+			GroupName:   cfg.Group,
+			Ephemeral:   true,
+		})
+		if err == nil {
+			zap.L().Info("nacos instance deregistered", zap.String("service", service))
+		}
+		return err
+	})
+	return nil
+}
+
+// 本机第一个非回环的IPv4地址
+func localIp() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", errors.New("nacos register: no non-loopback ipv4 address, set nacos.discovery.ip")
+}
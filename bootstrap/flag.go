@@ -3,22 +3,47 @@ package bootstrap
 import (
 	"flag"
 	"github.com/gin-gonic/gin"
-	"github.com/succko/hera/global"
+	"go.uber.org/zap"
+	"os"
 )
 
 var (
-	port = flag.String("port", "", "端口号: 默认以nacos配置为准")
-	env  = flag.String("env", "debug", "环境: debug-测试 release-生产")
+	port        = flag.String("port", "", "端口号: 默认以nacos配置为准")
+	env         = flag.String("env", "debug", "环境: debug-测试 release-生产")
+	printConfig = flag.Bool("print-config", false, "输出生效的配置及每个值的来源后退出")
 )
 
 // InitializeFlag 初始化flag
 func InitializeFlag() {
 	flag.Parse()
-	if *port != "" {
-		global.App.Config.App.Port = *port
-	}
 	if *env == gin.ReleaseMode {
-		global.App.Config.App.Env = *env
 		gin.SetMode(*env)
 	}
+	if err := ReloadConfig(); err != nil {
+		zap.L().Error("apply flags error", zap.Error(err))
+	}
+	if *printConfig {
+		if err := PrintConfig(os.Stdout); err != nil {
+			zap.L().Error("print config error", zap.Error(err))
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+// 命令行中显式指定的参数，优先于其他配置来源
+func flagLayer() map[string]any {
+	values := make(map[string]any)
+	if !flag.Parsed() {
+		return values
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			values["app.port"] = *port
+		case "env":
+			values["app.env"] = *env
+		}
+	})
+	return values
 }
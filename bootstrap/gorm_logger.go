@@ -0,0 +1,149 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/succko/hera/config"
+	"github.com/succko/hera/global"
+	"github.com/succko/hera/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 默认慢查询阈值
+const defaultSlowThreshold = 200 * time.Millisecond
+
+// 将 GORM 日志写入 zap，SQL、耗时、影响行数、调用位置与追踪ID作为结构化字段
+type gormLogger struct {
+	name  string // 连接名称
+	level logger.LogLevel
+	slow  time.Duration
+	lg    *zap.Logger
+}
+
+func getGormLogger(name string, cfg config.Database) logger.Interface {
+	var logMode logger.LogLevel
+
+	switch cfg.LogMode {
+	case "silent":
+		logMode = logger.Silent
+	case "error":
+		logMode = logger.Error
+	case "warn":
+		logMode = logger.Warn
+	case "info":
+		logMode = logger.Info
+	default:
+		logMode = logger.Info
+	}
+
+	slow := defaultSlowThreshold
+	if cfg.SlowThreshold != 0 {
+		slow = time.Duration(cfg.SlowThreshold) * time.Millisecond
+	}
+
+	// 启用独立日志文件时使用单独的 zap 实例，级别与全局日志一致
+	var lg *zap.Logger
+	if cfg.EnableFileLogWriter {
+		c := global.Config().Log
+		lg = zap.New(zapcore.NewCore(getEncoder(), getLogWriter(c.RootDir+"/"+cfg.LogFilename, c.MaxSize, c.MaxBackups, c.MaxAge), level))
+	}
+	return &gormLogger{name: name, level: logMode, slow: slow, lg: lg}
+}
+
+// 级别未启用时直接返回，避免为每条 SQL 取调用位置与构造字段
+func (l *gormLogger) log(ctx context.Context, lvl zapcore.Level, msg string, fields func() []zap.Field) {
+	lg := l.lg
+	if lg == nil {
+		// 每次取全局 logger，日志初始化晚于数据库连接时也能生效
+		lg = zap.L()
+	}
+	if !lg.Core().Enabled(lvl) {
+		return
+	}
+	base := []zap.Field{zap.String("db", l.name), zap.String("caller", gormCaller())}
+	if id := trace.FromContext(ctx); id != "" {
+		base = append(base, zap.String("trace_id", id))
+	}
+	if fields != nil {
+		base = append(base, fields()...)
+	}
+	// 调用位置取业务代码而不是本文件
+	if ce := lg.WithOptions(zap.WithCaller(false)).Check(lvl, msg); ce != nil {
+		ce.Write(base...)
+	}
+}
+
+// 跳过 GORM 及其插件与本文件后的第一个调用位置
+func gormCaller() string {
+	for i := 2; i < 20; i++ {
+		_, file, line, ok := runtime.Caller(i)
+		if !ok {
+			break
+		}
+		if strings.Contains(file, "gorm.io/") || strings.Contains(file, "glebarez/sqlite") || strings.HasSuffix(file, "bootstrap/gorm_logger.go") {
+			continue
+		}
+		return file + ":" + strconv.Itoa(line)
+	}
+	return ""
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	n := *l
+	n.level = level
+	return &n
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.log(ctx, zapcore.InfoLevel, fmt.Sprintf(msg, args...), nil)
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.log(ctx, zapcore.WarnLevel, fmt.Sprintf(msg, args...), nil)
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.log(ctx, zapcore.ErrorLevel, fmt.Sprintf(msg, args...), nil)
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	var (
+		lvl   zapcore.Level
+		msg   string
+		extra zap.Field
+	)
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		lvl, msg, extra = zapcore.ErrorLevel, "sql error", zap.Error(err)
+	case l.slow > 0 && elapsed > l.slow && l.level >= logger.Warn:
+		lvl, msg, extra = zapcore.WarnLevel, "slow sql", zap.Duration("threshold", l.slow)
+	case l.level >= logger.Info:
+		// 普通 SQL 以 Debug 级别输出，由 log.level 决定是否记录
+		lvl, msg, extra = zapcore.DebugLevel, "sql", zap.Skip()
+	default:
+		return
+	}
+	l.log(ctx, lvl, msg, func() []zap.Field {
+		sql, rows := fc()
+		return []zap.Field{zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("elapsed", elapsed), extra}
+	})
+}
@@ -0,0 +1,281 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/succko/hera/global"
+	"github.com/succko/hera/lifecycle"
+	"github.com/succko/hera/metadata"
+	"github.com/succko/hera/outbox"
+	"gorm.io/gorm"
+)
+
+// 过滤掉未启用的依赖模块
+func enabled(names ...string) []string {
+	modules := global.App.Modules
+	deps := make([]string, 0, len(names))
+	for _, name := range names {
+		var on bool
+		switch name {
+		case lifecycle.ModuleDb:
+			on = modules.Db
+		case lifecycle.ModuleValidator:
+			on = modules.Validator
+		case lifecycle.ModuleRedis:
+			on = modules.Redis
+		case lifecycle.ModuleXxl:
+			on = modules.Xxl
+		case lifecycle.ModuleMetadata:
+			on = modules.Metadata
+		case lifecycle.ModuleOss:
+			on = modules.Oss
+		case lifecycle.ModuleCron:
+			on = modules.Cron
+		case lifecycle.ModuleRocketmq:
+			on = modules.Rocketmq
+		case lifecycle.ModuleOutbox:
+			on = modules.Outbox
+		}
+		if on {
+			deps = append(deps, name)
+		}
+	}
+	return deps
+}
+
+// DbModule 数据库模块，包括默认连接与 databases 中声明的具名连接
+func DbModule() lifecycle.Module {
+	stopStats := func() {}
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleDb,
+		OnStart: func(ctx context.Context) error {
+			db, err := InitializeDB()
+			if err != nil {
+				return err
+			}
+			global.App.DB = db
+			dbs, err := InitializeDBs()
+			if err != nil {
+				return err
+			}
+			global.App.DBs = dbs
+			watchDB()
+			if global.Config().Metrics.Enable {
+				stopStats = startDBStats()
+			}
+			// 依赖数据库的模块在迁移完成后才启动
+			return autoMigrate(ctx)
+		},
+		OnStop: func(ctx context.Context) error {
+			stopStats()
+			var errs []error
+			for _, db := range allDBs() {
+				sqlDB, err := db.DB()
+				if err == nil {
+					err = sqlDB.Close()
+				}
+				errs = append(errs, err)
+			}
+			return errors.Join(errs...)
+		},
+		OnHealth: func(ctx context.Context) error {
+			if global.App.DB == nil {
+				return errors.New("database not initialized")
+			}
+			for name, db := range allDBs() {
+				sqlDB, err := db.DB()
+				if err == nil {
+					err = sqlDB.PingContext(ctx)
+				}
+				if err != nil {
+					return fmt.Errorf("database %s: %w", name, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// 默认连接与所有具名连接
+func allDBs() map[string]*gorm.DB {
+	dbs := make(map[string]*gorm.DB, len(global.App.DBs)+1)
+	if global.App.DB != nil {
+		dbs["default"] = global.App.DB
+	}
+	for name, db := range global.App.DBs {
+		dbs[name] = db
+	}
+	return dbs
+}
+
+// ValidatorModule 验证器模块
+func ValidatorModule() lifecycle.Module {
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleValidator,
+		OnStart: func(ctx context.Context) error {
+			return InitializeValidator()
+		},
+	}
+}
+
+// RedisModule Redis模块，包括默认实例与 redis_instances 中声明的具名实例
+func RedisModule() lifecycle.Module {
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleRedis,
+		OnStart: func(ctx context.Context) error {
+			client, err := InitializeRedis()
+			if err != nil {
+				return err
+			}
+			clients, err := InitializeRedises()
+			if err != nil {
+				_ = client.Close()
+				return err
+			}
+			global.App.Redis, global.App.RedisInstances = client, clients
+			global.App.Cache = newCache(client, global.Config().Cache)
+			watchRedis()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if global.App.Cache != nil {
+				_ = global.App.Cache.Close()
+			}
+			return closeRedis()
+		},
+		OnHealth: func(ctx context.Context) error {
+			if global.App.Redis == nil {
+				return errors.New("redis not initialized")
+			}
+			for name, client := range allRedis() {
+				if err := client.Ping(ctx).Err(); err != nil {
+					return fmt.Errorf("redis %s: %w", name, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// XxlModule Xxl执行器模块，任务依赖的数据模块在其停止后才关闭
+func XxlModule() lifecycle.Module {
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleXxl,
+		DependsOn:  enabled(lifecycle.ModuleDb, lifecycle.ModuleRedis, lifecycle.ModuleMetadata),
+		OnStart: func(ctx context.Context) error {
+			global.App.Xxl = InitializeXxl()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if global.App.Xxl != nil {
+				global.App.Xxl.Stop()
+			}
+			return nil
+		},
+	}
+}
+
+// MetadataModule 元数据模块，依赖数据库与Redis
+func MetadataModule() lifecycle.Module {
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleMetadata,
+		DependsOn:  enabled(lifecycle.ModuleDb, lifecycle.ModuleRedis),
+		OnStart: func(ctx context.Context) error {
+			metadata.Loader.InitializeMetadata()
+			return nil
+		},
+	}
+}
+
+// OssModule OSS模块
+func OssModule() lifecycle.Module {
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleOss,
+		OnStart: func(ctx context.Context) error {
+			global.App.Oss = InitializeOss()
+			if global.App.Oss == nil {
+				return errors.New("oss bucket initialize failed")
+			}
+			return nil
+		},
+	}
+}
+
+// CronModule 定时任务模块，在数据与元数据就绪后启动
+func CronModule() lifecycle.Module {
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleCron,
+		DependsOn:  enabled(lifecycle.ModuleDb, lifecycle.ModuleRedis, lifecycle.ModuleMetadata),
+		OnStart: func(ctx context.Context) error {
+			InitializeCron()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if c == nil {
+				return nil
+			}
+			// 等待正在执行的任务结束
+			select {
+			case <-c.Stop().Done():
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// RocketmqModule 消息模块，在数据与元数据就绪后开始消费，消息代理由 rokcetmq.broker 配置
+func RocketmqModule() lifecycle.Module {
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleRocketmq,
+		DependsOn:  enabled(lifecycle.ModuleDb, lifecycle.ModuleRedis, lifecycle.ModuleMetadata),
+		OnStart: func(ctx context.Context) error {
+			b, err := InitializeBroker()
+			if err != nil {
+				return err
+			}
+			global.App.Broker = b
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if global.App.Broker == nil {
+				return nil
+			}
+			err := global.App.Broker.Close()
+			global.App.Broker = nil
+			return err
+		},
+	}
+}
+
+// OutboxModule 发件箱模块，将事务中记录的消息发布到RocketMQ
+func OutboxModule() lifecycle.Module {
+	var relay *outbox.Relay
+	return &lifecycle.Hooks{
+		ModuleName: lifecycle.ModuleOutbox,
+		DependsOn:  enabled(lifecycle.ModuleDb, lifecycle.ModuleRedis, lifecycle.ModuleRocketmq),
+		OnStart: func(ctx context.Context) error {
+			if global.App.DB == nil {
+				return errors.New("outbox requires the db module")
+			}
+			if global.App.Broker == nil {
+				return errors.New("outbox requires the rocketmq module")
+			}
+			if err := outbox.Migrate(global.App.DB); err != nil {
+				return err
+			}
+			relay = outbox.NewRelay(global.App.DB, outbox.OptionsFrom(global.Config().Outbox))
+			relay.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if relay == nil {
+				return nil
+			}
+			return relay.Stop(ctx)
+		},
+	}
+}
@@ -3,7 +3,9 @@ package bootstrap
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/succko/hera/config"
 	"github.com/succko/hera/global"
+	"github.com/succko/hera/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -18,18 +20,32 @@ import (
 
 var lg *zap.Logger
 
+// 日志级别，配置热更新时生效
+var level = zap.NewAtomicLevel()
+
 func InitializeLog() *zap.Logger {
-	cfg := global.App.Config.Log
-	writeSyncer := getLogWriter(cfg.RootDir+"/"+global.App.Config.App.AppName+".log", cfg.MaxSize, cfg.MaxBackups, cfg.MaxAge)
+	cfg := global.Config().Log
+	writeSyncer := getLogWriter(cfg.RootDir+"/"+global.Config().App.AppName+".log", cfg.MaxSize, cfg.MaxBackups, cfg.MaxAge)
 	encoder := getEncoder()
-	var l = new(zapcore.Level)
-	_ = l.UnmarshalText([]byte(cfg.Level))
-	core := zapcore.NewCore(encoder, writeSyncer, l)
+	setLogLevel(cfg.Level)
+	core := zapcore.NewCore(encoder, writeSyncer, level)
 	lg = zap.New(core, zap.AddCaller())
 	zap.ReplaceGlobals(lg) // 替换zap包中全局的logger实例，后续在其他包中只需使用zap.L()调用即可
+	global.WatchConfig(func(old, new *config.Configuration, changes []config.Change) {
+		if config.Changed(changes, "log.level") {
+			setLogLevel(new.Log.Level)
+			zap.L().Info("log level changed", zap.String("level", level.String()))
+		}
+	})
 	return lg
 }
 
+func setLogLevel(text string) {
+	var l zapcore.Level
+	_ = l.UnmarshalText([]byte(text))
+	level.SetLevel(l)
+}
+
 func getEncoder() zapcore.Encoder {
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = func(time time.Time, encoder zapcore.PrimitiveArrayEncoder) {
@@ -52,6 +68,20 @@ func getLogWriter(filename string, maxSize, maxBackup, maxAge int) zapcore.Write
 	return zapcore.AddSync(lumberJackLogger)
 }
 
+// GinTrace 为每个请求设置追踪ID，优先使用请求头 X-Request-Id，并写入请求上下文与响应头。
+// 业务通过 db.WithContext(c.Request.Context()) 执行的 SQL 日志会携带同一追踪ID。
+func GinTrace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(trace.Header)
+		if id == "" {
+			id = trace.NewID()
+		}
+		c.Request = c.Request.WithContext(trace.NewContext(c.Request.Context(), id))
+		c.Header(trace.Header, id)
+		c.Next()
+	}
+}
+
 // GinLogger 接收gin框架默认的日志
 func GinLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -86,6 +116,7 @@ func GinLogger() gin.HandlerFunc {
 			zap.String("user-agent", c.Request.UserAgent()),
 			zap.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()),
 			zap.Duration("cost", cost),
+			zap.String("trace_id", trace.FromContext(c.Request.Context())),
 		)
 	}
 }
@@ -0,0 +1,158 @@
+package bootstrap
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/succko/hera/global"
+	"gorm.io/gorm"
+)
+
+// 默认的指标路径与连接池指标采集间隔
+const (
+	defaultMetricsPath     = "/metrics"
+	defaultMetricsInterval = 15 * time.Second
+)
+
+var (
+	metricsOnce sync.Once
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "hera_db_query_duration_seconds",
+		Help:    "SQL execution time by connection, table and operation.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .2, .5, 1, 2.5, 5},
+	}, []string{"db", "table", "operation"})
+	dbOpenConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hera_db_open_connections",
+		Help: "Number of established connections, both in use and idle.",
+	}, []string{"db"})
+	dbInUseConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hera_db_in_use_connections",
+		Help: "Number of connections currently in use.",
+	}, []string{"db"})
+	dbIdleConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hera_db_idle_connections",
+		Help: "Number of idle connections.",
+	}, []string{"db"})
+	dbWaitCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hera_db_wait_count",
+		Help: "Total number of connections waited for.",
+	}, []string{"db"})
+	dbWaitDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hera_db_wait_duration_seconds",
+		Help: "Total time blocked waiting for a new connection.",
+	}, []string{"db"})
+)
+
+// 注册指标，多次调用只注册一次
+func registerMetrics() {
+	metricsOnce.Do(func() {
+		prometheus.MustRegister(dbQueryDuration, dbOpenConnections, dbInUseConnections, dbIdleConnections, dbWaitCount, dbWaitDuration)
+	})
+}
+
+// 在 HTTP 服务上暴露指标
+func metricsRoute(r *gin.Engine) {
+	cfg := global.Config().Metrics
+	if !cfg.Enable {
+		return
+	}
+	registerMetrics()
+	path := cfg.Path
+	if path == "" {
+		path = defaultMetricsPath
+	}
+	r.GET(path, gin.WrapH(promhttp.Handler()))
+}
+
+// 定期采集各数据库连接池的状态，返回停止采集的函数
+func startDBStats() func() {
+	registerMetrics()
+	interval := defaultMetricsInterval
+	if i := global.Config().Metrics.Interval; i > 0 {
+		interval = time.Duration(i) * time.Second
+	}
+	quit := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			collectDBStats()
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(quit) })
+	}
+}
+
+func collectDBStats() {
+	for name, db := range allDBs() {
+		sqlDB, err := db.DB()
+		if err != nil {
+			continue
+		}
+		s := sqlDB.Stats()
+		dbOpenConnections.WithLabelValues(name).Set(float64(s.OpenConnections))
+		dbInUseConnections.WithLabelValues(name).Set(float64(s.InUse))
+		dbIdleConnections.WithLabelValues(name).Set(float64(s.Idle))
+		dbWaitCount.WithLabelValues(name).Set(float64(s.WaitCount))
+		dbWaitDuration.WithLabelValues(name).Set(s.WaitDuration.Seconds())
+	}
+}
+
+// 记录 SQL 执行耗时的 GORM 插件
+type metricsPlugin struct {
+	name string
+}
+
+const metricsStartKey = "hera:metrics:start"
+
+func (p *metricsPlugin) Name() string {
+	return "hera:metrics"
+}
+
+func (p *metricsPlugin) Initialize(db *gorm.DB) error {
+	registerMetrics()
+	before := func(db *gorm.DB) {
+		db.InstanceSet(metricsStartKey, time.Now())
+	}
+	after := func(operation string) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			v, ok := db.InstanceGet(metricsStartKey)
+			if !ok {
+				return
+			}
+			start, _ := v.(time.Time)
+			dbQueryDuration.WithLabelValues(p.name, db.Statement.Table, operation).Observe(time.Since(start).Seconds())
+		}
+	}
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("hera:metrics:before_create", before),
+		cb.Create().After("gorm:create").Register("hera:metrics:after_create", after("create")),
+		cb.Query().Before("gorm:query").Register("hera:metrics:before_query", before),
+		cb.Query().After("gorm:query").Register("hera:metrics:after_query", after("query")),
+		cb.Update().Before("gorm:update").Register("hera:metrics:before_update", before),
+		cb.Update().After("gorm:update").Register("hera:metrics:after_update", after("update")),
+		cb.Delete().Before("gorm:delete").Register("hera:metrics:before_delete", before),
+		cb.Delete().After("gorm:delete").Register("hera:metrics:after_delete", after("delete")),
+		cb.Row().Before("gorm:row").Register("hera:metrics:before_row", before),
+		cb.Row().After("gorm:row").Register("hera:metrics:after_row", after("row")),
+		cb.Raw().Before("gorm:raw").Register("hera:metrics:before_raw", before),
+		cb.Raw().After("gorm:raw").Register("hera:metrics:after_raw", after("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/succko/hera/global"
+	"github.com/succko/hera/migrate"
+)
+
+// 基于默认数据库连接与注册的迁移创建迁移器
+func newMigrator() (*migrate.Migrator, error) {
+	if global.App.DB == nil {
+		return nil, errors.New("migrate requires the db module")
+	}
+	cfg := global.Config().Migrate
+	return migrate.New(global.App.DB, global.App.RunConfig.Migrations, migrate.Options{
+		Table:       cfg.Table,
+		LockTimeout: time.Duration(cfg.LockTimeout) * time.Second,
+	})
+}
+
+// 启动时执行未执行的迁移，仅在配置 migrate.auto 时生效
+func autoMigrate(ctx context.Context) error {
+	if !global.Config().Migrate.Auto || len(global.App.RunConfig.Migrations) == 0 {
+		return nil
+	}
+	m, err := newMigrator()
+	if err != nil {
+		return err
+	}
+	return m.Up(ctx)
+}
+
+// RunMigrateCommand 处理命令行 migrate status|up|down [N]|to VERSION，执行后返回 true，调用方应随后退出
+func RunMigrateCommand(w io.Writer) (bool, error) {
+	cmdArgs := commandArgs()
+	if len(cmdArgs) == 0 || cmdArgs[0] != "migrate" {
+		return false, nil
+	}
+	db, err := InitializeDB()
+	if err != nil {
+		return true, err
+	}
+	global.App.DB = db
+	defer func() {
+		if db, err := global.App.DB.DB(); err == nil {
+			_ = db.Close()
+		}
+	}()
+	m, err := newMigrator()
+	if err != nil {
+		return true, err
+	}
+
+	ctx := context.Background()
+	args := cmdArgs[1:]
+	cmd := "status"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch cmd {
+	case "status":
+	case "up":
+		err = m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return true, fmt.Errorf("migrate down: invalid steps %q", args[1])
+			}
+		}
+		err = m.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			return true, errors.New("migrate to: version is required")
+		}
+		version, perr := strconv.ParseInt(args[1], 10, 64)
+		if perr != nil || version < 0 {
+			return true, fmt.Errorf("migrate to: invalid version %q", args[1])
+		}
+		err = m.To(ctx, version)
+	default:
+		return true, fmt.Errorf("unknown migrate command %q, expected status, up, down [N] or to VERSION", cmd)
+	}
+	if err != nil {
+		return true, err
+	}
+
+	// 输出执行后的状态
+	status, err := m.Status()
+	if err != nil {
+		return true, err
+	}
+	for _, s := range status {
+		state := "pending"
+		if s.Applied {
+			state = "applied " + s.AppliedAt.Format(time.DateTime)
+		}
+		if _, err := fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, state); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// 命令行中的非参数部分。未启用 Flag 模块时 flag.Parse 不会执行，
+// 此时用同名参数的副本解析，只取子命令，不改变参数的值
+func commandArgs() []string {
+	if flag.Parsed() {
+		return flag.Args()
+	}
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flag.VisitAll(func(f *flag.Flag) {
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			fs.Bool(f.Name, false, f.Usage)
+			return
+		}
+		fs.String(f.Name, f.DefValue, f.Usage)
+	})
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return nil
+	}
+	return fs.Args()
+}
@@ -2,6 +2,8 @@ package bootstrap
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
@@ -9,29 +11,31 @@ import (
 	"github.com/succko/hera/global"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
-	"os"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 var wg sync.WaitGroup
 
-func InitializeNacosConfig() error {
+// 配置与服务发现共用的 Nacos 客户端配置
+func nacosClientConfig() (constant.ClientConfig, []constant.ServerConfig) {
 	// 创建clientConfig的另一种方式
 	clientConfig := *constant.NewClientConfig(
-		constant.WithNamespaceId(global.App.Config.Nacos.Namespace), //当namespace是public时，此处填空字符串。
+		constant.WithNamespaceId(global.Config().Nacos.Namespace), //当namespace是public时，此处填空字符串。
 		constant.WithTimeoutMs(5000),
 		constant.WithNotLoadCacheAtStart(true),
 		constant.WithLogDir("/tmp/nacos/log"),
 		constant.WithCacheDir("/tmp/nacos/cache"),
 		constant.WithLogLevel("debug"),
-		constant.WithUsername(global.App.Config.Nacos.Username),
-		constant.WithPassword(global.App.Config.Nacos.Password),
+		constant.WithUsername(global.Config().Nacos.Username),
+		constant.WithPassword(global.Config().Nacos.Password.Value()),
 	)
 
 	// 创建serverConfig的另一种方式
-	serverConfigs := make([]constant.ServerConfig, len(global.App.Config.Nacos.Servers))
-	for i, server := range global.App.Config.Nacos.Servers {
+	serverConfigs := make([]constant.ServerConfig, len(global.Config().Nacos.Servers))
+	for i, server := range global.Config().Nacos.Servers {
 		serverConfigs[i] = *constant.NewServerConfig(
 			server.ServerAddr,
 			server.Port,
@@ -39,31 +43,44 @@ func InitializeNacosConfig() error {
 			constant.WithContextPath("/nacos"),
 		)
 	}
+	return clientConfig, serverConfigs
+}
 
-	if len(global.App.RunConfig.Nacos) == 0 {
-		global.App.RunConfig.Nacos = make(map[string]any)
-	}
-	global.App.RunConfig.Nacos[global.App.Config.App.AppName+"-"+gin.Mode()+".yaml"] = &global.App.Config
+func InitializeNacosConfig() error {
+	clientConfig, serverConfigs := nacosClientConfig()
+
+	// 主配置叠加在配置文件之上，其余为业务注册的配置
+	mainDataId := global.Config().App.AppName + "-" + gin.Mode() + ".yaml"
 	m := global.App.RunConfig.Nacos
-	wg.Add(len(m))
+	errs := make(chan error, len(m)+1)
+	wg.Add(len(m) + 1)
+	go func() {
+		defer wg.Done()
+		errs <- listenConfig(mainDataId, applyNacosConfig, clientConfig, serverConfigs)
+	}()
 	for k, v := range m {
 		k := k
-		v := v
+		apply := nacosTarget(k, v)
 		go func() {
 			defer wg.Done()
-			err := listenConfig(k, v, clientConfig, serverConfigs)
-			if err != nil {
-				zap.L().Error("listen config error", zap.Error(err))
-			}
-			zap.L().Info("listen config", zap.String("dataId", k))
+			errs <- listenConfig(k, apply, clientConfig, serverConfigs)
 		}()
 	}
 	wg.Wait()
+	close(errs)
+	var all []error
+	for err := range errs {
+		all = append(all, err)
+	}
+	if err := errors.Join(all...); err != nil {
+		return err
+	}
 	zap.L().Info("nacos config initialized")
 	return nil
 }
 
-func listenConfig[T any](DataId string, t T, clientConfig constant.ClientConfig, serverConfigs []constant.ServerConfig) error {
+// 监听 Nacos 配置，apply 解析并应用新内容，解析失败时保留原配置
+func listenConfig(DataId string, apply func(content string) error, clientConfig constant.ClientConfig, serverConfigs []constant.ServerConfig) error {
 	// 创建动态配置客户端的另一种方式 (推荐)
 	configClient, err := clients.NewConfigClient(
 		vo.NacosClientParam{
@@ -72,49 +89,70 @@ func listenConfig[T any](DataId string, t T, clientConfig constant.ClientConfig,
 		},
 	)
 	if err != nil {
-		zap.L().Panic("初始化配置失败", zap.Error(err))
+		return fmt.Errorf("nacos config client: %w", err)
 	}
 	Group := "DEFAULT_GROUP"
 	// 将配置赋值给全局变量
 	param := vo.ConfigParam{DataId: DataId, Group: Group}
-	content, err := configClient.GetConfig(param)
-	if strings.HasSuffix(DataId, ".yaml") {
-		parseYaml(content, t)
-	} else {
-		parseJson(content, t)
+	// 获取失败时仍然监听，配置发布后再应用
+	if content, err := configClient.GetConfig(param); err != nil {
+		zap.L().Error("nacos get config error", zap.String("dataId", DataId), zap.Error(err))
+	} else if err := apply(content); err != nil {
+		return fmt.Errorf("nacos config %s: %w", DataId, err)
 	}
+	zap.L().Info("listen config", zap.String("dataId", DataId))
 	// 监听配置文件
 	param.OnChange = func(namespace, group, dataId, data string) {
-		// 重载配置
-		if strings.HasSuffix(DataId, ".yaml") {
-			// 脱敏日志
-			zap.L().Info("config file changed, group:" + group + ", dataId:" + dataId)
-			parseYaml(content, t)
-		} else {
-			zap.L().Info("config file changed, group:" + group + ", dataId:" + dataId + ", data:" + data)
-			parseJson(content, t)
+		// 重载配置，日志中不输出配置内容以免泄露密钥
+		zap.L().Info("config file changed, group:" + group + ", dataId:" + dataId)
+		if err := apply(data); err != nil {
+			zap.L().Error("config reload rejected, group:"+group+", dataId:"+dataId, zap.Error(err))
 		}
 	}
-	err = configClient.ListenConfig(param)
-	return err
+	return configClient.ListenConfig(param)
+}
+
+// 可原子替换的业务配置，见 global.NacosConfig
+type nacosLoader interface {
+	Load(content []byte, unmarshal func([]byte, any) error) error
 }
 
-func parseYaml[T any](data string, t T) {
-	if err := yaml.Unmarshal([]byte(data), t); err != nil {
-		zap.L().Error("初始化配置失败", zap.Error(err))
-		return
+// 业务配置的应用函数。global.NacosConfig 在每次变更时原子替换；
+// 其他指针只在首次加载时写入，之后的变更需要重启才能生效，避免与读取方产生数据竞争
+func nacosTarget(dataId string, t any) func(content string) error {
+	unmarshal := json.Unmarshal
+	if strings.HasSuffix(dataId, ".yaml") {
+		unmarshal = yaml.Unmarshal
+	}
+	if l, ok := t.(nacosLoader); ok {
+		return func(content string) error {
+			return l.Load([]byte(content), unmarshal)
+		}
+	}
+	var loaded atomic.Bool
+	return func(content string) error {
+		if loaded.Load() {
+			zap.L().Warn("nacos config changed, restart to apply or register a *global.NacosConfig for hot reload", zap.String("dataId", dataId))
+			return nil
+		}
+		if err := parseInto(content, t, unmarshal); err != nil {
+			return err
+		}
+		loaded.Store(true)
+		return nil
 	}
-	zap.L().Info("初始化配置成功", zap.Any("config", global.App.Config))
-	// 处理OSS环境变量
-	_ = os.Setenv("OSS_ACCESS_KEY_ID", global.App.Config.Oss.AccessKeyID)
-	_ = os.Setenv("OSS_ACCESS_KEY_SECRET", global.App.Config.Oss.AccessKeySecret)
-	_ = os.Setenv("OSS_SESSION_TOKEN", global.App.Config.Oss.OssSessionToken)
 }
 
-func parseJson[T any](data string, t T) {
-	if err := json.Unmarshal([]byte(data), t); err != nil {
-		zap.L().Error("初始化配置失败", zap.Error(err))
-		return
+// 将内容解析为与 t 同类型的新值，成功后整体替换 t 指向的值
+func parseInto(content string, t any, unmarshal func([]byte, any) error) error {
+	rv := reflect.ValueOf(t)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("nacos config target must be a non-nil pointer, got %T", t)
 	}
-	zap.L().Info("初始化配置成功", zap.Any("config", t))
+	fresh := reflect.New(rv.Elem().Type())
+	if err := unmarshal([]byte(content), fresh.Interface()); err != nil {
+		return err
+	}
+	rv.Elem().Set(fresh.Elem())
+	return nil
 }
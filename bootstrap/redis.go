@@ -2,21 +2,181 @@ package bootstrap
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/go-redis/redis/v8"
+	"github.com/succko/hera/config"
 	"github.com/succko/hera/global"
 	"go.uber.org/zap"
 )
 
-func InitializeRedis() *redis.Client {
-	client := redis.NewClient(&redis.Options{
-		Addr:     global.App.Config.Redis.Host + ":" + global.App.Config.Redis.Port,
-		Password: global.App.Config.Redis.Password, // no password set
-		DB:       global.App.Config.Redis.DB,       // use default DB
-	})
-	_, err := client.Ping(context.Background()).Result()
+// 启动时连接重试的等待时间，每次翻倍直到上限
+const (
+	redisRetryWait    = 500 * time.Millisecond
+	redisRetryMaxWait = 5 * time.Second
+)
+
+// InitializeRedis 连接默认的Redis实例
+func InitializeRedis() (redis.UniversalClient, error) {
+	return connectRedis("default", global.Config().Redis)
+}
+
+// InitializeRedises 连接 redis_instances 中声明的具名实例，任一失败时关闭已建立的连接
+func InitializeRedises() (map[string]redis.UniversalClient, error) {
+	clients := make(map[string]redis.UniversalClient, len(global.Config().RedisInstances))
+	for name, cfg := range global.Config().RedisInstances {
+		client, err := connectRedis(name, cfg)
+		if err != nil {
+			for _, c := range clients {
+				_ = c.Close()
+			}
+			return nil, err
+		}
+		clients[strings.ToLower(name)] = client
+	}
+	return clients, nil
+}
+
+// 连接失败时按退避时间重试，重试耗尽后返回包含模式与地址的错误
+func connectRedis(name string, cfg config.Redis) (redis.UniversalClient, error) {
+	retries := cfg.ConnectRetries
+	if retries == 0 {
+		retries = 3
+	}
+	wait := redisRetryWait
+	for attempt := 0; ; attempt++ {
+		client, err := newRedisClient(cfg)
+		if err == nil {
+			zap.L().Info("redis connected", zap.String("name", name), zap.String("mode", redisMode(cfg)), zap.Strings("addrs", redisAddrs(cfg)))
+			return client, nil
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("redis %s: connect %s %s failed after %d attempts: %w", name, redisMode(cfg), strings.Join(redisAddrs(cfg), ","), attempt+1, err)
+		}
+		zap.L().Warn("redis connect failed, retrying", zap.String("name", name), zap.Int("attempt", attempt+1), zap.Duration("wait", wait), zap.Error(err))
+		time.Sleep(wait)
+		if wait *= 2; wait > redisRetryMaxWait {
+			wait = redisRetryMaxWait
+		}
+	}
+}
+
+func newRedisClient(cfg config.Redis) (redis.UniversalClient, error) {
+	tlsConfig, err := redisTls(cfg.Tls)
 	if err != nil {
-		zap.L().Error("Redis connect ping failed, err:", zap.Any("err", err))
-		return nil
+		return nil, err
+	}
+	opts := &redis.UniversalOptions{
+		Addrs:            redisAddrs(cfg),
+		MasterName:       cfg.MasterName,
+		DB:               cfg.DB,
+		Username:         cfg.Username,
+		Password:         cfg.Password.Value(),
+		SentinelPassword: cfg.SentinelPassword.Value(),
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		DialTimeout:      time.Duration(cfg.DialTimeout) * time.Millisecond,
+		ReadTimeout:      time.Duration(cfg.ReadTimeout) * time.Millisecond,
+		WriteTimeout:     time.Duration(cfg.WriteTimeout) * time.Millisecond,
+		PoolTimeout:      time.Duration(cfg.PoolTimeout) * time.Millisecond,
+		MaxRetries:       cfg.MaxRetries,
+		TLSConfig:        tlsConfig,
+	}
+	var client redis.UniversalClient
+	switch redisMode(cfg) {
+	case config.RedisCluster:
+		client = redis.NewClusterClient(opts.Cluster())
+	case config.RedisSentinel:
+		client = redis.NewFailoverClient(opts.Failover())
+	default:
+		client = redis.NewClient(opts.Simple())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func redisMode(cfg config.Redis) string {
+	if cfg.Mode == "" {
+		return config.RedisStandalone
 	}
-	return client
+	return cfg.Mode
+}
+
+// 单节点模式下未配置 addrs 时使用 host:port
+func redisAddrs(cfg config.Redis) []string {
+	if len(cfg.Addrs) == 0 {
+		return []string{net.JoinHostPort(cfg.Host, cfg.Port)}
+	}
+	return cfg.Addrs
+}
+
+func redisTls(cfg config.RedisTls) (*tls.Config, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.CaFile != "" {
+		pem, err := os.ReadFile(cfg.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis tls: no certificate found in %s", cfg.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// 默认实例与所有具名实例
+func allRedis() map[string]redis.UniversalClient {
+	clients := make(map[string]redis.UniversalClient, len(global.App.RedisInstances)+1)
+	if global.App.Redis != nil {
+		clients["default"] = global.App.Redis
+	}
+	for name, client := range global.App.RedisInstances {
+		clients[name] = client
+	}
+	return clients
+}
+
+func closeRedis() error {
+	var errs []error
+	for _, client := range allRedis() {
+		errs = append(errs, client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// 配置中的Redis连接变更时提示重启。ws 集群、锁、缓存等组件在启动时持有客户端，运行期间替换会使其继续使用已关闭的连接
+func watchRedis() {
+	global.WatchConfig(func(old, new *config.Configuration, changes []config.Change) {
+		if config.Changed(changes, "redis", "redis_instances", "cache") {
+			zap.L().Warn("redis config changed, restart to apply")
+		}
+	})
 }
@@ -2,91 +2,263 @@ package bootstrap
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"github.com/apache/rocketmq-client-go/v2"
 	"github.com/apache/rocketmq-client-go/v2/consumer"
 	"github.com/apache/rocketmq-client-go/v2/primitive"
 	"github.com/apache/rocketmq-client-go/v2/producer"
 	"github.com/gin-gonic/gin"
+	"github.com/succko/hera/broker"
+	"github.com/succko/hera/config"
 	"github.com/succko/hera/global"
+	heramq "github.com/succko/hera/mq"
 	"go.uber.org/zap"
+	"reflect"
+	"runtime"
+	"strings"
 )
 
 type mq struct{}
 
 var Mq = new(mq)
 
-func InitializeRocketMqConsumers() []rocketmq.PushConsumer {
-	var consumers []rocketmq.PushConsumer
-	mqConsumers := global.App.RunConfig.RocketMqConsumers
-	if len(mqConsumers) > 0 {
-		for k, v := range mqConsumers {
-			consumers = append(consumers, initializeRocketMqConsumer(k, v))
+const (
+	defaultMaxRetries  = 16
+	defaultDlqSuffix   = "_DLQ"
+	defaultConcurrency = 20
+)
+
+// 汇总代码与配置中声明的消费者，补全默认消费组并校验
+func consumerSpecs() ([]config.Consumer, error) {
+	run := global.App.RunConfig
+	var specs []config.Consumer
+	// 代码中声明的消费者以函数名作为处理函数名称，用于区分同一主题的默认消费组
+	for k, v := range run.RocketMqConsumers {
+		specs = append(specs, config.Consumer{Topic: k, Handler: heramq.Wrap(v), HandlerName: funcName(v)})
+	}
+	for k, v := range run.RocketMqHandlers {
+		specs = append(specs, config.Consumer{Topic: k, Handler: v, HandlerName: funcName(v)})
+	}
+	for _, spec := range run.RocketMqSpecs {
+		if spec.HandlerName == "" {
+			spec.HandlerName = funcName(spec.Handler)
 		}
+		specs = append(specs, spec)
 	}
-	return consumers
+	for _, spec := range global.Config().Rokcetmq.Consumers {
+		h, ok := run.RocketMqNamed[spec.HandlerName]
+		if !ok {
+			return nil, fmt.Errorf("rocketmq consumer %s: handler %q not registered", spec.Topic, spec.HandlerName)
+		}
+		spec.Handler = h
+		specs = append(specs, spec)
+	}
+
+	// 同一主题有多个未指定消费组的消费者时，默认消费组附加选择器或处理函数名称
+	shared := make(map[string]int)
+	for _, spec := range specs {
+		if spec.Group == "" {
+			shared[spec.Topic]++
+		}
+	}
+	groups := make(map[string]string)
+	for i := range specs {
+		spec := &specs[i]
+		if spec.Topic == "" || spec.Handler == nil {
+			return nil, fmt.Errorf("rocketmq consumer %q: topic and handler are required", spec.Topic)
+		}
+		if spec.Tag != "" && spec.Sql != "" {
+			return nil, fmt.Errorf("rocketmq consumer %s: tag and sql are mutually exclusive", spec.Topic)
+		}
+		if spec.Group == "" && shared[spec.Topic] > 1 {
+			if suffix := groupSuffix(*spec); suffix != "" {
+				spec.Group = defaultGroup(spec.Topic) + "-" + suffix
+			}
+		}
+		*spec = specDefaults(*spec)
+		// 同一进程内一个消费组只能有一个消费者，无法区分的多个消费者需指定不同的消费组
+		if topic, ok := groups[spec.Group]; ok {
+			return nil, fmt.Errorf("rocketmq consumer group %s used by both %s and %s, set group explicitly", spec.Group, topic, spec.Topic)
+		}
+		groups[spec.Group] = spec.Topic
+	}
+	return specs, nil
 }
 
-func initializeRocketMqConsumer(topic string, f func(message []byte)) rocketmq.PushConsumer {
-	return initializeRocketMqConsumerWithTag(topic, "", f)
+// 补全消费者声明中未指定的消费组、并发数与顺序消费
+func specDefaults(spec config.Consumer) config.Consumer {
+	cfg := global.Config().Rokcetmq
+	if spec.Group == "" {
+		spec.Group = defaultGroup(spec.Topic)
+	}
+	if spec.Concurrency <= 0 {
+		spec.Concurrency = cfg.Concurrency
+	}
+	if spec.Concurrency <= 0 {
+		spec.Concurrency = defaultConcurrency
+	}
+	if spec.Orderly == nil {
+		orderly := cfg.Orderly
+		spec.Orderly = &orderly
+	}
+	return spec
 }
 
-func initializeRocketMqConsumerWithTag(topic string, tag string, f func(message []byte)) rocketmq.PushConsumer {
-	c, err := rocketmq.NewPushConsumer(
-		consumer.WithGroupName(topic+"-"+global.App.Config.App.AppName+"-"+gin.Mode()),
-		consumer.WithNsResolver(primitive.NewPassthroughResolver([]string{global.App.Config.Rokcetmq.Addr})),
-		consumer.WithConsumerModel(consumer.Clustering),
-		consumer.WithConsumeFromWhere(consumer.ConsumeFromLastOffset),
-	)
+// 默认消费组 {topic}-{app_name}-{gin_mode}
+func defaultGroup(topic string) string {
+	return topic + "-" + global.Config().App.AppName + "-" + gin.Mode()
+}
 
-	if err != nil {
-		zap.L().Error("Failed to create consumer", zap.Error(err))
-		return nil
+// 区分同一主题多个消费者的默认消费组后缀，依次取标签、SQL 表达式摘要与处理函数名称
+func groupSuffix(spec config.Consumer) string {
+	if tag := strings.TrimSpace(spec.Tag); tag != "" && tag != "*" {
+		var tags []string
+		for _, t := range strings.Split(tag, "||") {
+			tags = append(tags, strings.TrimSpace(t))
+		}
+		return groupName(strings.Join(tags, "_"))
 	}
+	if spec.Sql != "" {
+		sum := sha1.Sum([]byte(spec.Sql))
+		return "sql_" + hex.EncodeToString(sum[:4])
+	}
+	return groupName(spec.HandlerName)
+}
 
-	var selector consumer.MessageSelector
-	if tag != "" {
-		selector = consumer.MessageSelector{
-			Type:       consumer.TAG,
-			Expression: tag,
+// 消费组名称只允许字母、数字、下划线、中划线与 %|，其余字符替换为下划线
+func groupName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '%' || r == '|' {
+			return r
 		}
-	} else {
-		selector = consumer.MessageSelector{}
+		return '_'
+	}, s)
+}
+
+// 函数名，去掉包路径，如 order.handleCreated
+func funcName(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+func initializeRocketMqConsumer(spec config.Consumer) (rocketmq.PushConsumer, error) {
+	cfg := global.Config().Rokcetmq
+	spec = specDefaults(spec)
+	concurrency := spec.Concurrency
+	orderly := *spec.Orderly
+	model := consumer.Clustering
+	if spec.Broadcasting {
+		model = consumer.BroadCasting
+	}
+	opts := []consumer.Option{
+		consumer.WithGroupName(spec.Group),
+		consumer.WithNsResolver(primitive.NewPassthroughResolver([]string{cfg.Addr})),
+		consumer.WithConsumerModel(model),
+		// 消息在回调中同步处理，并发数由消费协程数限制
+		consumer.WithConsumeGoroutineNums(concurrency),
+		consumer.WithConsumerOrder(orderly),
+		// 超过重试次数后由 subscribe 投递到死信主题，投递失败时仍可再重试一次
+		consumer.WithMaxReconsumeTimes(maxRetries() + 1),
+	}
+	switch spec.FromWhere {
+	case "", config.ConsumeFromLast:
+		opts = append(opts, consumer.WithConsumeFromWhere(consumer.ConsumeFromLastOffset))
+	case config.ConsumeFromFirst:
+		opts = append(opts, consumer.WithConsumeFromWhere(consumer.ConsumeFromFirstOffset))
+	case config.ConsumeFromTimestamp:
+		opts = append(opts, consumer.WithConsumeFromWhere(consumer.ConsumeFromTimestamp), consumer.WithConsumeTimestamp(spec.Timestamp))
+	default:
+		return nil, fmt.Errorf("unknown from_where %q", spec.FromWhere)
+	}
+	c, err := rocketmq.NewPushConsumer(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := consumer.MessageSelector{}
+	if spec.Tag != "" {
+		selector = consumer.MessageSelector{Type: consumer.TAG, Expression: spec.Tag}
+	} else if spec.Sql != "" {
+		selector = consumer.MessageSelector{Type: consumer.SQL92, Expression: spec.Sql}
 	}
 
 	// 订阅的消费者列表
-	subscribe(c, topic, selector, f)
+	if err = subscribe(c, spec.Topic, selector, spec.Handler, orderly); err != nil {
+		return nil, err
+	}
 
 	if err = c.Start(); err != nil {
-		zap.L().Error("Failed to start consumer", zap.Error(err))
-		return nil
+		return nil, err
 	}
-
-	return c
+	zap.L().Info("rocketmq consumer started", zap.String("topic", spec.Topic), zap.String("group", spec.Group), zap.String("tag", spec.Tag), zap.String("sql", spec.Sql), zap.Bool("orderly", orderly), zap.Bool("broadcasting", spec.Broadcasting))
+	return c, nil
 }
 
-func subscribe(c rocketmq.PushConsumer, topic string, selector consumer.MessageSelector, f func(message []byte)) {
+func subscribe(c rocketmq.PushConsumer, topic string, selector consumer.MessageSelector, h heramq.Handler, orderly bool) error {
+	// 顺序消费失败时暂停当前队列，并发消费失败时稍后重试
+	retry := consumer.ConsumeRetryLater
+	if orderly {
+		retry = consumer.SuspendCurrentQueueAMoment
+	}
 	// 订阅消息
-	err := c.Subscribe(topic, selector, func(ctx context.Context,
+	return c.Subscribe(topic, selector, func(ctx context.Context,
 		msgs ...*primitive.MessageExt) (consumer.ConsumeResult, error) {
+		result := consumer.ConsumeSuccess
 		for _, msg := range msgs {
 			zap.L().Info(fmt.Sprintf("Consumer subscribe callback, topic: %s, msg: %s", topic, msg))
-			go f(msg.Body)
-			// retry TODO
+			err := heramq.Handle(ctx, h, msg)
+			if err == nil {
+				continue
+			}
+			zap.L().Error("Consumer handle error", zap.String("topic", topic), zap.String("msgId", msg.MsgId), zap.Int32("reconsumeTimes", msg.ReconsumeTimes), zap.Error(err))
+			if msg.ReconsumeTimes >= maxRetries() && sendDlq(ctx, topic, msg, err) {
+				continue
+			}
+			result = retry
 		}
-		return consumer.ConsumeSuccess, nil
+		return result, nil
 	})
+}
+
+func maxRetries() int32 {
+	if n := global.Config().Rokcetmq.MaxRetries; n > 0 {
+		return n
+	}
+	return defaultMaxRetries
+}
+
+// 将多次消费失败的消息投递到死信主题，返回是否投递成功
+func sendDlq(ctx context.Context, topic string, msg *primitive.MessageExt, cause error) bool {
+	suffix := global.Config().Rokcetmq.DlqSuffix
+	if suffix == "" {
+		suffix = defaultDlqSuffix
+	}
+	if global.App.RocketMqProducer == nil {
+		zap.L().Error("Consumer send dlq error: producer not initialized", zap.String("topic", topic), zap.String("msgId", msg.MsgId))
+		return false
+	}
+	dlq := broker.NewDlqMessage(topic, suffix, msg, cause)
+	res, err := global.App.RocketMqProducer.SendSync(ctx, dlq)
 	if err != nil {
-		zap.L().Error("Failed to subscribe", zap.Error(err))
+		zap.L().Error("Consumer send dlq error", zap.String("topic", dlq.Topic), zap.String("msgId", msg.MsgId), zap.Error(err))
+		return false
 	}
+	zap.L().Warn("Consumer message sent to dlq", zap.String("topic", dlq.Topic), zap.String("msgId", msg.MsgId), zap.String("dlqMsgId", res.MsgID))
+	return true
 }
 
 func InitializeRocketMqProducer() rocketmq.Producer {
-	p, err := rocketmq.NewProducer(
-		producer.WithNsResolver(primitive.NewPassthroughResolver([]string{global.App.Config.Rokcetmq.Addr})),
-		producer.WithRetry(16),
-		producer.WithGroupName(global.App.Config.App.AppName),
-	)
+	p, err := rocketmq.NewProducer(producerOptions(global.Config().App.AppName)...)
 
 	if err != nil {
 		zap.L().Error("Failed to create producer", zap.Error(err))
@@ -101,3 +273,29 @@ func InitializeRocketMqProducer() rocketmq.Producer {
 
 	return p
 }
+
+// InitializeRocketMqTransactionProducer 初始化事务生产者，未注册事务回查函数时返回 nil
+func InitializeRocketMqTransactionProducer() (rocketmq.TransactionProducer, error) {
+	checker := global.App.RunConfig.RocketMqChecker
+	if checker == nil {
+		return nil, nil
+	}
+	p, err := rocketmq.NewTransactionProducer(heramq.NewTransactionListener(checker), producerOptions(global.Config().App.AppName+"-tx")...)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.Start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func producerOptions(group string) []producer.Option {
+	return []producer.Option{
+		producer.WithNsResolver(primitive.NewPassthroughResolver([]string{global.Config().Rokcetmq.Addr})),
+		producer.WithRetry(16),
+		producer.WithGroupName(group),
+		// 指定分区键的消息按分区键选择队列，保证顺序
+		producer.WithQueueSelector(producer.NewHashQueueSelector()),
+	}
+}
@@ -0,0 +1,75 @@
+package bootstrap
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/succko/hera/config"
+	"github.com/succko/hera/global"
+)
+
+func handleOrder(ctx context.Context, msg *primitive.MessageExt) error { return nil }
+
+func consumeOrder(message []byte) {}
+
+// 同一主题的多个消费者未指定消费组时使用可区分的默认消费组
+func TestConsumerSpecsGroups(t *testing.T) {
+	global.SwapConfig(&config.Configuration{App: config.App{AppName: "hera-test"}})
+	run := global.App.RunConfig
+	defer func() { global.App.RunConfig = run }()
+
+	global.App.RunConfig.RocketMqConsumers = map[string]func(message []byte){"order": consumeOrder, "user": consumeOrder}
+	global.App.RunConfig.RocketMqHandlers = map[string]func(ctx context.Context, msg *primitive.MessageExt) error{"order": handleOrder}
+	global.App.RunConfig.RocketMqSpecs = []config.Consumer{
+		{Topic: "order", Tag: "TagA || TagB", Handler: handleOrder},
+		{Topic: "order", Sql: "a > 1", Handler: handleOrder},
+	}
+	specs, err := consumerSpecs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := make(map[string]bool)
+	for _, spec := range specs {
+		groups[spec.Group] = true
+	}
+	base := defaultGroup("order")
+	for _, want := range []string{
+		base + "-bootstrap_consumeOrder",
+		base + "-bootstrap_handleOrder",
+		base + "-TagA_TagB",
+		defaultGroup("user"),
+	} {
+		if !groups[want] {
+			t.Errorf("group %s not found in %v", want, groups)
+		}
+	}
+	for g := range groups {
+		if strings.HasPrefix(g, base+"-sql_") {
+			return
+		}
+	}
+	t.Errorf("sql group not found in %v", groups)
+}
+
+// 无法区分的消费者需要显式指定消费组
+func TestConsumerSpecsGroupConflict(t *testing.T) {
+	global.SwapConfig(&config.Configuration{App: config.App{AppName: "hera-test"}})
+	run := global.App.RunConfig
+	defer func() { global.App.RunConfig = run }()
+
+	global.App.RunConfig.RocketMqConsumers = nil
+	global.App.RunConfig.RocketMqHandlers = nil
+	global.App.RunConfig.RocketMqSpecs = []config.Consumer{
+		{Topic: "order", Handler: handleOrder},
+		{Topic: "order", Handler: handleOrder},
+	}
+	if _, err := consumerSpecs(); err == nil || !strings.Contains(err.Error(), "set group explicitly") {
+		t.Fatalf("consumerSpecs() error = %v", err)
+	}
+	global.App.RunConfig.RocketMqSpecs[1].Group = "order-audit"
+	if _, err := consumerSpecs(); err != nil {
+		t.Fatal(err)
+	}
+}
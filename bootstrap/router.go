@@ -1,61 +1,66 @@
 package bootstrap
 
 import (
+	"bufio"
 	"context"
-	"github.com/gin-contrib/cache"
-	"github.com/gin-contrib/cache/persistence"
+	"errors"
+	"fmt"
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/soheilhy/cmux"
+	"github.com/succko/hera/config"
 	"github.com/succko/hera/global"
+	"github.com/succko/hera/pb"
 	"github.com/succko/hera/routes"
 	"github.com/succko/hera/ws"
 	"go.uber.org/zap"
-	"golang.org/x/net/websocket"
 	"google.golang.org/grpc"
 	"io"
-	"log"
 	"net"
 	"net/http"
-	"os"
-	"os/signal"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 )
 
+// WebSocket 连接路径
+const wsPath = "/ws"
+
 // 设置路由
 func setupRouter() *gin.Engine {
-	if global.App.Config.App.Env == gin.ReleaseMode {
+	if global.Config().App.Env == gin.ReleaseMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New()
 
-	// 静态文件 缓存测试
-	inMemoryStore := persistence.NewInMemoryStore(60 * time.Second)
-	r.GET("/cache_ping", cache.CachePage(inMemoryStore, time.Minute, func(c *gin.Context) {
-		r.StaticFile("/test", "./static/test.json")
-	}))
-
 	// 使用自定义的日志和恢复中间件
 	//r.Use(gin.Logger(), gin.Recovery())
-	r.Use(GinLogger(), GinRecovery(true))
+	r.Use(GinTrace(), GinLogger(), GinRecovery(true))
+
+	// 注册 Prometheus 指标路由
+	metricsRoute(r)
 
 	// 注册 ping 路由
 	r.GET("/ping", func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "pong")
 	})
 
+	// 注册健康检查路由，任一模块不健康时返回 503
+	r.GET("/health", health)
+
 	// 注册 ws 路由
 	if global.App.Modules.Ws {
-		r.GET("/ws", func(ctx *gin.Context) {
+		r.GET(wsPath, func(ctx *gin.Context) {
 			ws.ServeWs(ctx.Writer, ctx.Request)
 		})
 	}
 
 	// 注册 api 分组路由
-	global.App.RunConfig.Router(r)
+	if global.App.RunConfig.Router != nil {
+		global.App.RunConfig.Router(r)
+	}
 
 	// 注册 swagger 分组路由
 	if global.App.Modules.Swagger {
@@ -68,77 +73,312 @@ func setupRouter() *gin.Engine {
 }
 
 // RunCMux 运行 CMux
-func RunCMux() {
+//
+// mux 模式下 gRPC、WebSocket 与 HTTP 共用 App.Port：
+// HTTP/2 且 content-type 为 application/grpc 的连接交给 gRPC，
+// 路径为 /ws 的 WebSocket 升级请求交给 Hub，其余连接交给 HTTP。
+// separate 模式下各协议分别监听独立端口。
+func RunCMux() error {
+	if global.Config().Ports.Mode == config.PortsModeSeparate {
+		return runSeparate()
+	}
+
 	// 创建 TCP 监听器
-	l, err := net.Listen("tcp", ":"+global.App.Config.App.Port)
+	l, err := net.Listen("tcp", ":"+global.Config().App.Port)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return err
+	}
+
+	// Hub 先于 gRPC 启动，Pala 服务依赖 Hub
+	if global.App.Modules.Ws {
+		if err := RunHub(); err != nil {
+			return err
+		}
 	}
 
 	// 创建 CMux 实例
 	m := cmux.New(l)
-	// 创建 gRPC 匹配规则
-	//grpcL := m.Match(cmux.HTTP2HeaderField("content-type", "application/grpc"))
-	// 创建 ws 匹配规则
-	//wsL := m.Match(cmux.HTTP1HeaderField("Upgrade", "websocket"))
-	// 否则使用 HTTP 匹配规则
-	httpL := m.Match(cmux.Any())
-
-	// 启动 gRPC 服务器
-	//go RunGrpcServer(grpcL)
+	// 匹配规则按注册顺序生效
 	if global.App.Modules.Grpc {
-		go RunGrpcServer()
+		// grpc-go 客户端在收到 SETTINGS 帧前不会发送请求头，因此需要先回写 SETTINGS
+		grpcL := m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+		RunGrpcServer(grpcL)
 	}
 	if global.App.Modules.Ws {
-		//go RunWsServer(wsL)
-		// 启动 WebSocket 服务器
-		go ws.SingletonHub().Run()
+		RunWsServer(m.Match(wsMatcher))
 	}
 	if global.App.Modules.Http {
 		// 启动 HTTP 服务器
-		go RunHttpServer(httpL)
+		RunHttpServer(m.Match(cmux.Any()))
 	}
 
+	// 关闭时首先停止接收新连接
+	Shutdown.Register(StageListener, "cmux", func(ctx context.Context) error {
+		m.Close()
+		return nil
+	})
+
 	// 启动 CMux
-	if err := m.Serve(); !strings.Contains(err.Error(), "use of closed network connection") {
-		zap.L().DPanic("CMux Serve error", zap.Error(err))
+	go func() {
+		if err := m.Serve(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			zap.L().Error("CMux Serve error", zap.Error(err))
+			Shutdown.Trigger()
+		}
+	}()
+	return nil
+}
+
+// 各协议使用独立端口
+func runSeparate() error {
+	if global.App.Modules.Ws {
+		if err := RunHub(); err != nil {
+			return err
+		}
 	}
+	if global.App.Modules.Grpc {
+		l, err := net.Listen("tcp", ":"+GrpcPort())
+		if err != nil {
+			return err
+		}
+		RunGrpcServer(l)
+	}
+	if global.App.Modules.Ws {
+		// 未配置独立端口时，WebSocket 通过 HTTP 路由 /ws 提供，未启用 HTTP 时单独监听 app.port
+		port := global.Config().Ports.WsPort
+		if port == "" && !global.App.Modules.Http {
+			port = global.Config().App.Port
+		}
+		if port != "" {
+			l, err := net.Listen("tcp", ":"+port)
+			if err != nil {
+				return err
+			}
+			RunWsServer(l)
+		}
+	}
+	if global.App.Modules.Http {
+		l, err := net.Listen("tcp", ":"+global.Config().App.Port)
+		if err != nil {
+			return err
+		}
+		RunHttpServer(l)
+	}
+	return nil
+}
+
+// GrpcPort separate 模式及独立运行 gRPC 时使用的端口
+func GrpcPort() string {
+	if port := global.Config().Ports.GrpcPort; port != "" {
+		return port
+	}
+	port, _ := strconv.Atoi(global.Config().App.Port)
+	return strconv.Itoa(port + 10000)
+}
+
+// 匹配路径为 /ws 的 WebSocket 升级请求，其余升级请求仍交给 HTTP 路由处理
+func wsMatcher(r io.Reader) bool {
+	req, err := http.ReadRequest(bufio.NewReader(r))
+	if err != nil {
+		return false
+	}
+	return req.URL.Path == wsPath && websocket.IsWebSocketUpgrade(req)
 }
 
 // RunGrpcServer 运行 gRPC 服务器
-func RunGrpcServer() {
+func RunGrpcServer(l net.Listener) {
+	servingPort("grpc", l)
 	// 创建 gRPC 服务器实例
 	server := grpc.NewServer()
 	// 注册服务
-	global.App.RunConfig.Grpc(server)
-	//pb.RegisterUserServer(s, UserServer)
-	//pb.RegisterTestServer(s, TestServer)
-	//pb.RegisterPalaServer(s, PalaServer)
+	if global.App.RunConfig.Grpc != nil {
+		global.App.RunConfig.Grpc(server)
+	}
+	// 同时启用 WebSocket 时内置 Pala 服务，业务已自行注册时不再注册
+	if global.App.Modules.Ws {
+		if _, ok := server.GetServiceInfo()[pb.Pala_ServiceDesc.ServiceName]; !ok {
+			pb.RegisterPalaServer(server, ws.NewPalaServer())
+		}
+	}
+
+	// 优雅关闭：等待进行中的调用完成，超时后强制关闭
+	Shutdown.Register(StageServer, "grpc", func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			return ctx.Err()
+		}
+	})
 
 	// 启动 gRPC 服务器
-	port, _ := strconv.Atoi(global.App.Config.App.Port)
-	lis, _ := net.Listen("tcp", ":"+strconv.Itoa(port+10000))
-	if err := server.Serve(lis); err != cmux.ErrListenerClosed {
-		zap.L().DPanic("gRPC server error", zap.Error(err))
+	go func() {
+		if err := server.Serve(l); err != nil && err != grpc.ErrServerStopped && err != cmux.ErrListenerClosed {
+			zap.L().Error("gRPC server error", zap.Error(err))
+			Shutdown.Trigger()
+		}
+	}()
+}
+
+var hubOnce sync.Once
+
+// RunHub 运行 WebSocket Hub
+func RunHub() (err error) {
+	hubOnce.Do(func() {
+		h := ws.SingletonHub()
+		cfg := global.Config().Ws
+		prefix := cfg.Prefix
+		if prefix == "" {
+			prefix = "hera:ws:" + global.Config().App.AppName
+		}
+		if err = setupAuthenticator(h, prefix); err != nil {
+			return
+		}
+		if err = setupOfflineStore(h, prefix); err != nil {
+			return
+		}
+		if cfg.ReportTopic != "" && global.App.Broker == nil {
+			err = errors.New("ws report topic requires the rocketmq module")
+			return
+		}
+		h.SetReportTopic(cfg.ReportTopic)
+		if cfg.Ack.Enable {
+			h.EnableAck(ws.AckOptions{MaxRetries: cfg.Ack.MaxRetries, Backoff: time.Duration(cfg.Ack.Backoff) * time.Millisecond})
+		}
+		// 集群模式通过 Redis 在多个节点间投递消息
+		if cfg.Cluster {
+			if global.App.Redis == nil {
+				err = errors.New("ws cluster requires the redis module")
+				return
+			}
+			ttl := 180 * time.Second
+			if cfg.PresenceTtl > 0 {
+				ttl = time.Duration(cfg.PresenceTtl) * time.Second
+			}
+			if err = h.EnableCluster(global.App.Redis, prefix, ttl); err != nil {
+				return
+			}
+		}
+		go h.Run()
+		// 向所有客户端发送关闭帧
+		Shutdown.Register(StageServer, "ws hub", h.Shutdown)
+	})
+	return err
+}
+
+// 根据配置设置客户端认证方式，已通过 Hub.SetAuthenticator 自定义时不再设置
+func setupAuthenticator(h *ws.Hub, prefix string) error {
+	if h.Authenticator() != nil {
+		return nil
+	}
+	cfg := global.Config().Ws.Auth
+	secret := cfg.Secret.Value()
+	if secret == "" && cfg.Type == "jwt" {
+		secret = global.Config().Jwt.Secret.Value()
+	}
+	tenants := make(map[int64]string, len(cfg.TenantSecrets))
+	for k, v := range cfg.TenantSecrets {
+		id, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ws auth tenant id %q", k)
+		}
+		tenants[id] = v.Value()
+	}
+	if secret == "" && len(tenants) == 0 {
+		return errors.New("ws auth requires a secret")
+	}
+
+	opts := ws.AuthOptions{Skew: time.Duration(cfg.Skew) * time.Second}
+	nonce := cfg.Nonce
+	if nonce == "" {
+		nonce = "memory"
+		if global.Config().Ws.Cluster {
+			nonce = "redis"
+		}
+	}
+	switch nonce {
+	case "none":
+	case "memory":
+		opts.Nonces = ws.NewMemoryNonceStore()
+	case "redis":
+		if global.App.Redis == nil {
+			return errors.New("ws auth redis nonce store requires the redis module")
+		}
+		opts.Nonces = ws.NewRedisNonceStore(global.App.Redis, prefix)
+	default:
+		return fmt.Errorf("unknown ws auth nonce store %q", nonce)
+	}
+
+	secrets := ws.StaticSecrets(secret, tenants)
+	var authenticator ws.Authenticator
+	switch cfg.Type {
+	case "", "md5":
+		authenticator = ws.NewMd5Authenticator(secrets, opts)
+	case "hmac":
+		authenticator = ws.NewHmacAuthenticator(secrets, opts)
+	case "jwt":
+		authenticator = ws.NewJwtAuthenticator(secrets, opts)
+	default:
+		return fmt.Errorf("unknown ws auth type %q", cfg.Type)
+	}
+	h.SetAuthenticator(authenticator, time.Duration(cfg.Grace)*time.Second)
+	return nil
+}
+
+// 根据配置设置离线消息存储
+func setupOfflineStore(h *ws.Hub, prefix string) error {
+	cfg := global.Config().Ws.Offline
+	opts := ws.OfflineOptions{Cap: cfg.Cap, Ttl: time.Duration(cfg.Ttl) * time.Second}
+	switch cfg.Store {
+	case "":
+		return nil
+	case "memory":
+		h.SetOfflineStore(ws.NewMemoryOfflineStore(opts))
+	case "redis":
+		if global.App.Redis == nil {
+			return errors.New("ws offline redis store requires the redis module")
+		}
+		h.SetOfflineStore(ws.NewRedisOfflineStore(global.App.Redis, prefix, opts))
+	case "gorm":
+		if global.App.DB == nil {
+			return errors.New("ws offline gorm store requires the db module")
+		}
+		store, err := ws.NewGormOfflineStore(global.App.DB, opts)
+		if err != nil {
+			return err
+		}
+		h.SetOfflineStore(store)
+	default:
+		return fmt.Errorf("unknown ws offline store %q", cfg.Store)
 	}
+	return nil
 }
 
 // RunWsServer 运行 WebSocket 服务器
 func RunWsServer(l net.Listener) {
+	servingPort("ws", l)
 	// 创建 WebSocket 服务器
-	server := &http.Server{Handler: websocket.Handler(func(conn *websocket.Conn) {
-		if _, err := io.Copy(conn, conn); err != nil {
-			panic(err)
-		}
-	})}
+	mux := http.NewServeMux()
+	mux.HandleFunc(wsPath, ws.ServeWs)
+	server := &http.Server{Handler: mux}
+	Shutdown.Register(StageServer, "ws", server.Shutdown)
 	// 启动 WebSocket 服务器
-	if err := server.Serve(l); err != cmux.ErrListenerClosed {
-		zap.L().Error("WebSocket server error", zap.Error(err))
-	}
+	go func() {
+		if err := server.Serve(l); err != nil && err != http.ErrServerClosed && err != cmux.ErrListenerClosed {
+			zap.L().Error("WebSocket server error", zap.Error(err))
+			Shutdown.Trigger()
+		}
+	}()
 }
 
 // RunHttpServer 运行 HTTP 服务器
 func RunHttpServer(l net.Listener) {
+	servingPort("http", l)
 	// 设置路由
 	r := setupRouter()
 
@@ -155,27 +395,29 @@ func RunHttpServer(l net.Listener) {
 		Handler: r,
 	}
 
+	// 停止接收新请求，并等待进行中的请求处理完成
+	Shutdown.Register(StageServer, "http", s.Shutdown)
+
 	// 启动 HTTP 服务器
 	go func() {
-		if err := s.Serve(l); err != nil && err != cmux.ErrListenerClosed {
-			zap.L().DPanic("HTTP server error", zap.Error(err))
+		if err := s.Serve(l); err != nil && err != http.ErrServerClosed && err != cmux.ErrListenerClosed {
+			zap.L().Error("HTTP server error", zap.Error(err))
+			Shutdown.Trigger()
 		}
 	}()
+}
 
-	// 等待中断信号以优雅地关闭服务器（设置 5 秒的超时时间）
-	quit := make(chan os.Signal)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	zap.L().Info("Shutdown Server ...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := s.Shutdown(ctx); err != nil {
-		zap.L().Fatal("Server Shutdown:", zap.Error(err))
-	}
-	select {
-	case <-ctx.Done():
-		zap.L().Info("timeout of 5 seconds.")
+// 返回各模块的健康状态，例如 {"db":"ok","redis":"redis default: dial tcp ..."}
+func health(ctx *gin.Context) {
+	status, result := http.StatusOK, gin.H{}
+	if global.App.Lifecycle != nil {
+		for name, err := range global.App.Lifecycle.Health(ctx.Request.Context()) {
+			if err != nil {
+				status, result[name] = http.StatusServiceUnavailable, err.Error()
+				continue
+			}
+			result[name] = "ok"
+		}
 	}
-	zap.L().Info("Server exiting")
+	ctx.JSON(status, result)
 }
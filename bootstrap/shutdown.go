@@ -0,0 +1,123 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/succko/hera/global"
+	"go.uber.org/zap"
+)
+
+// 关闭阶段，按顺序执行，同一阶段内的钩子并行执行
+const (
+	StageListener = iota // 停止接收新连接
+	StageServer          // 等待进行中的请求处理完成
+	StageModule          // 停止各模块并释放资源
+	stageCount
+)
+
+// 默认的排空超时时间
+const defaultShutdownTimeout = 5 * time.Second
+
+type shutdownHook struct {
+	name string
+	f    func(ctx context.Context) error
+}
+
+type shutdown struct {
+	mu      sync.Mutex
+	hooks   [stageCount][]shutdownHook
+	trigger chan struct{}
+	once    sync.Once
+	done    chan struct{}
+}
+
+// Shutdown 全局关闭协调器
+var Shutdown = &shutdown{
+	trigger: make(chan struct{}),
+	done:    make(chan struct{}),
+}
+
+// Register 注册关闭钩子
+func (s *shutdown) Register(stage int, name string, f func(ctx context.Context) error) {
+	if stage < 0 || stage >= stageCount {
+		panic(fmt.Sprintf("shutdown: invalid stage %d", stage))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks[stage] = append(s.hooks[stage], shutdownHook{name: name, f: f})
+}
+
+// Trigger 主动触发关闭，例如服务异常退出时
+func (s *shutdown) Trigger() {
+	s.once.Do(func() {
+		close(s.trigger)
+	})
+}
+
+// Done 关闭流程结束后关闭的通道
+func (s *shutdown) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait 阻塞直到收到 SIGINT/SIGTERM 或 Trigger 被调用，然后按阶段执行关闭钩子
+func (s *shutdown) Wait() error {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case sig := <-quit:
+		zap.L().Info("Shutdown Server ...", zap.String("signal", sig.String()))
+	case <-s.trigger:
+		zap.L().Info("Shutdown Server ...", zap.String("signal", "trigger"))
+	}
+	return s.run()
+}
+
+func (s *shutdown) run() error {
+	defer close(s.done)
+
+	timeout := defaultShutdownTimeout
+	if t := global.Config().App.ShutdownTimeout; t > 0 {
+		timeout = time.Duration(t) * time.Second
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	var errs []error
+	for stage, stageHooks := range hooks {
+		// 每个阶段单独计时，避免排空耗尽后模块没有时间释放资源
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		var (
+			wg  sync.WaitGroup
+			emu sync.Mutex
+		)
+		wg.Add(len(stageHooks))
+		for _, h := range stageHooks {
+			go func(h shutdownHook) {
+				defer wg.Done()
+				if err := h.f(ctx); err != nil {
+					zap.L().Error("shutdown hook error", zap.Int("stage", stage), zap.String("name", h.name), zap.Error(err))
+					emu.Lock()
+					errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+					emu.Unlock()
+					return
+				}
+				zap.L().Info("shutdown hook done", zap.Int("stage", stage), zap.String("name", h.name))
+			}(h)
+		}
+		wg.Wait()
+		cancel()
+	}
+	zap.L().Info("Server exiting")
+	return errors.Join(errs...)
+}
@@ -10,12 +10,12 @@ import (
 
 func InitializeXxl() xxl.Executor {
 	exec := xxl.NewExecutor(
-		xxl.ServerAddr(global.App.Config.Xxl.ServerAddr),
-		xxl.AccessToken(global.App.Config.Xxl.AccessToken), //请求令牌(默认为空)
-		//xxl.ExecutorIp(global.App.Config.Xxl.ExecutorIp),     //可自动获取
-		xxl.ExecutorPort(global.App.Config.App.Port),   //默认9999（非必填）
-		xxl.RegistryKey(global.App.Config.App.AppName), //执行器名称
-		xxl.SetLogger(&xxlLogger{}),                    //自定义日志
+		xxl.ServerAddr(global.Config().Xxl.ServerAddr),
+		xxl.AccessToken(global.Config().Xxl.AccessToken.Value()), //请求令牌(默认为空)
+		//xxl.ExecutorIp(global.Config().Xxl.ExecutorIp),     //可自动获取
+		xxl.ExecutorPort(global.Config().App.Port),   //默认9999（非必填）
+		xxl.RegistryKey(global.Config().App.AppName), //执行器名称
+		xxl.SetLogger(&xxlLogger{}),                  //自定义日志
 	)
 	exec.Init()
 	//设置日志查看handler
@@ -0,0 +1,49 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/succko/hera/config"
+)
+
+// ErrClosed 消息代理已关闭
+var ErrClosed = errors.New("broker closed")
+
+// Broker 消息代理，屏蔽具体的消息中间件。
+//
+// 消息与消费者声明沿用 RocketMQ 的 primitive.Message 与 config.Consumer，
+// 以便处理函数在不同实现之间无需修改。
+type Broker interface {
+	// Publish 发布消息，返回时消息已被代理接收。
+	Publish(ctx context.Context, msg *primitive.Message) error
+	// Subscribe 按声明订阅消息，需在发布前完成订阅。
+	Subscribe(spec config.Consumer) error
+	// Close 停止消费并释放资源。
+	Close() error
+}
+
+// Handle 执行消息处理函数，将panic转换为错误
+func Handle(ctx context.Context, h func(ctx context.Context, msg *primitive.MessageExt) error, msg *primitive.MessageExt) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("consumer panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return h(ctx, msg)
+}
+
+// NewDlqMessage 构造多次消费失败的消息对应的死信消息，死信主题为原主题加 suffix
+func NewDlqMessage(topic, suffix string, msg *primitive.MessageExt, cause error) *primitive.Message {
+	dlq := primitive.NewMessage(topic+suffix, msg.Body)
+	dlq.WithProperties(msg.GetProperties())
+	dlq.WithProperty("ORIGIN_TOPIC", topic)
+	dlq.WithProperty("ORIGIN_MSG_ID", msg.MsgId)
+	dlq.WithProperty("RECONSUME_TIMES", strconv.Itoa(int(msg.ReconsumeTimes)))
+	dlq.WithProperty("DLQ_REASON", cause.Error())
+	return dlq
+}
@@ -0,0 +1,251 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/succko/hera/config"
+	"go.uber.org/zap"
+)
+
+// 延时等级对应的延时，与 RocketMQ 默认配置一致
+var delayLevels = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute, 5 * time.Minute,
+	6 * time.Minute, 7 * time.Minute, 8 * time.Minute, 9 * time.Minute, 10 * time.Minute,
+	20 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour,
+}
+
+// MemoryOptions 进程内消息代理选项
+type MemoryOptions struct {
+	MaxRetries int32         // 消费失败的最大重试次数，默认16，超过后投递到死信主题
+	RetryDelay time.Duration // 重试间隔，默认100ms
+	DlqSuffix  string        // 死信主题后缀，默认 _DLQ
+	Buffer     int           // 每个订阅的队列长度，默认1024
+}
+
+func (o MemoryOptions) withDefaults() MemoryOptions {
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = 16
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = 100 * time.Millisecond
+	}
+	if o.DlqSuffix == "" {
+		o.DlqSuffix = "_DLQ"
+	}
+	if o.Buffer <= 0 {
+		o.Buffer = 1024
+	}
+	return o
+}
+
+// memory 进程内的消息代理，基于通道投递，用于单元测试与本地开发。
+// 每个订阅相当于一个消费组，订阅同一主题的多个消费组各自收到全部消息。
+type memory struct {
+	opts   MemoryOptions
+	mu     sync.RWMutex
+	closed bool // 关闭后不再登记后台任务，与 tasks.Wait 互斥
+	subs   map[string][]*subscription
+	seq    atomic.Int64
+	quit   chan struct{}
+	once   sync.Once
+	tasks  sync.WaitGroup
+}
+
+type subscription struct {
+	spec  config.Consumer
+	tags  []string // 为空时接收全部标签
+	queue chan *primitive.MessageExt
+}
+
+// NewMemory 创建进程内消息代理
+func NewMemory(opts MemoryOptions) Broker {
+	return &memory{
+		opts: opts.withDefaults(),
+		subs: make(map[string][]*subscription),
+		quit: make(chan struct{}),
+	}
+}
+
+func (b *memory) Publish(ctx context.Context, msg *primitive.Message) error {
+	select {
+	case <-b.quit:
+		return ErrClosed
+	default:
+	}
+	level, _ := strconv.Atoi(msg.GetProperty(primitive.PropertyDelayTimeLevel))
+	if level > 0 && level <= len(delayLevels) {
+		b.after(delayLevels[level-1], func() {
+			_ = b.publish(context.Background(), msg)
+		})
+		return nil
+	}
+	return b.publish(ctx, msg)
+}
+
+func (b *memory) publish(ctx context.Context, msg *primitive.Message) error {
+	b.mu.RLock()
+	subs := b.subs[msg.Topic]
+	b.mu.RUnlock()
+	for _, s := range subs {
+		if !s.match(msg.GetTags()) {
+			continue
+		}
+		ext := b.newMessageExt(msg)
+		select {
+		case s.queue <- ext:
+		case <-b.quit:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *memory) newMessageExt(msg *primitive.Message) *primitive.MessageExt {
+	ext := &primitive.MessageExt{
+		MsgId:          strconv.FormatInt(b.seq.Add(1), 10),
+		BornTimestamp:  time.Now().UnixMilli(),
+		StoreTimestamp: time.Now().UnixMilli(),
+	}
+	ext.Topic = msg.Topic
+	ext.Body = msg.Body
+	ext.WithProperties(msg.GetProperties())
+	return ext
+}
+
+func (b *memory) Subscribe(spec config.Consumer) error {
+	if spec.Topic == "" || spec.Handler == nil {
+		return errors.New("topic and handler are required")
+	}
+	if spec.Sql != "" {
+		return errors.New("memory broker does not support sql selectors")
+	}
+	s := &subscription{spec: spec, queue: make(chan *primitive.MessageExt, b.opts.Buffer)}
+	if expr := strings.TrimSpace(spec.Tag); expr != "" && expr != "*" {
+		for _, tag := range strings.Split(expr, "||") {
+			s.tags = append(s.tags, strings.TrimSpace(tag))
+		}
+	}
+	// 顺序消费时单协程逐条处理
+	workers := spec.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	orderly := spec.Orderly != nil && *spec.Orderly
+	if orderly {
+		workers = 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.subs[spec.Topic] = append(b.subs[spec.Topic], s)
+	b.tasks.Add(workers)
+	for i := 0; i < workers; i++ {
+		go b.consume(s, orderly)
+	}
+	return nil
+}
+
+func (s *subscription) match(tag string) bool {
+	if len(s.tags) == 0 {
+		return true
+	}
+	for _, t := range s.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *memory) consume(s *subscription, orderly bool) {
+	defer b.tasks.Done()
+	for {
+		select {
+		case <-b.quit:
+			return
+		case msg := <-s.queue:
+			for b.handle(s, msg) && orderly {
+				// 顺序消费失败时原地重试，保证后续消息不会越过当前消息
+				select {
+				case <-time.After(b.opts.RetryDelay):
+				case <-b.quit:
+					return
+				}
+			}
+		}
+	}
+}
+
+// 处理一条消息，返回是否需要重试
+func (b *memory) handle(s *subscription, msg *primitive.MessageExt) (retry bool) {
+	err := Handle(context.Background(), s.spec.Handler, msg)
+	if err == nil {
+		return false
+	}
+	zap.L().Error("memory broker handle error", zap.String("topic", msg.Topic), zap.String("msgId", msg.MsgId), zap.Int32("reconsumeTimes", msg.ReconsumeTimes), zap.Error(err))
+	if msg.ReconsumeTimes >= b.opts.MaxRetries {
+		b.sendDlq(msg, err)
+		return false
+	}
+	msg.ReconsumeTimes++
+	if s.spec.Orderly != nil && *s.spec.Orderly {
+		return true
+	}
+	b.after(b.opts.RetryDelay, func() {
+		select {
+		case s.queue <- msg:
+		case <-b.quit:
+		}
+	})
+	return false
+}
+
+// 延时执行f，代理关闭时取消
+func (b *memory) after(d time.Duration, f func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.tasks.Add(1)
+	go func() {
+		defer b.tasks.Done()
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			f()
+		case <-b.quit:
+		}
+	}()
+}
+
+func (b *memory) sendDlq(msg *primitive.MessageExt, cause error) {
+	dlq := NewDlqMessage(msg.Topic, b.opts.DlqSuffix, msg, cause)
+	if err := b.publish(context.Background(), dlq); err != nil {
+		zap.L().Error("memory broker send dlq error", zap.String("topic", dlq.Topic), zap.Error(err))
+	}
+}
+
+func (b *memory) Close() error {
+	b.once.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+		close(b.quit)
+	})
+	b.tasks.Wait()
+	return nil
+}
@@ -0,0 +1,204 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/succko/hera/config"
+)
+
+// 收集消息体，超时未收到时测试失败
+func receive(t *testing.T, ch <-chan string, n int) []string {
+	t.Helper()
+	var got []string
+	for i := 0; i < n; i++ {
+		select {
+		case v := <-ch:
+			got = append(got, v)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("received %v, want %d messages", got, n)
+		}
+	}
+	return got
+}
+
+func collect(ch chan<- string) func(ctx context.Context, msg *primitive.MessageExt) error {
+	return func(ctx context.Context, msg *primitive.MessageExt) error {
+		ch <- string(msg.Body)
+		return nil
+	}
+}
+
+func TestMemoryPublishSubscribe(t *testing.T) {
+	b := NewMemory(MemoryOptions{})
+	defer b.Close()
+	all, tagged := make(chan string, 10), make(chan string, 10)
+	if err := b.Subscribe(config.Consumer{Topic: "order", Handler: collect(all)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Subscribe(config.Consumer{Topic: "order", Tag: "paid || refund", Handler: collect(tagged)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"created", "paid", "refund"} {
+		msg := primitive.NewMessage("order", []byte(tag))
+		msg.WithTag(tag)
+		if err := b.Publish(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 每个订阅各自收到匹配的全部消息
+	if got := receive(t, all, 3); len(got) != 3 {
+		t.Fatalf("all = %v", got)
+	}
+	got := receive(t, tagged, 2)
+	if !(got[0] == "paid" && got[1] == "refund" || got[0] == "refund" && got[1] == "paid") {
+		t.Fatalf("tagged = %v", got)
+	}
+	select {
+	case v := <-tagged:
+		t.Fatalf("unexpected message %q", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMemorySubscribeErrors(t *testing.T) {
+	b := NewMemory(MemoryOptions{})
+	h := func(ctx context.Context, msg *primitive.MessageExt) error { return nil }
+	if err := b.Subscribe(config.Consumer{Handler: h}); err == nil {
+		t.Fatal("Subscribe() without topic, want error")
+	}
+	if err := b.Subscribe(config.Consumer{Topic: "order", Sql: "a > 1", Handler: h}); err == nil {
+		t.Fatal("Subscribe() with sql, want error")
+	}
+	_ = b.Close()
+	if err := b.Subscribe(config.Consumer{Topic: "order", Handler: h}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Subscribe() after close = %v, want ErrClosed", err)
+	}
+	if err := b.Publish(context.Background(), primitive.NewMessage("order", nil)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish() after close = %v, want ErrClosed", err)
+	}
+}
+
+func TestMemoryDelay(t *testing.T) {
+	b := NewMemory(MemoryOptions{})
+	defer b.Close()
+	ch := make(chan string, 1)
+	if err := b.Subscribe(config.Consumer{Topic: "order", Handler: collect(ch)}); err != nil {
+		t.Fatal(err)
+	}
+	msg := primitive.NewMessage("order", []byte("delayed"))
+	msg.WithDelayTimeLevel(1)
+	start := time.Now()
+	if err := b.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, ch, 1)
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("delivered after %v, want at least 1s", elapsed)
+	}
+}
+
+// 处理失败与panic时重试，超过最大重试次数后投递到死信主题
+func TestMemoryRetryDlq(t *testing.T) {
+	b := NewMemory(MemoryOptions{MaxRetries: 2, RetryDelay: time.Millisecond})
+	defer b.Close()
+	var calls atomic.Int32
+	if err := b.Subscribe(config.Consumer{Topic: "order", Handler: func(ctx context.Context, msg *primitive.MessageExt) error {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		return errors.New("failed")
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	dlq := make(chan *primitive.MessageExt, 1)
+	if err := b.Subscribe(config.Consumer{Topic: "order_DLQ", Handler: func(ctx context.Context, msg *primitive.MessageExt) error {
+		dlq <- msg
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish(context.Background(), primitive.NewMessage("order", []byte("x"))); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-dlq:
+		if string(msg.Body) != "x" || msg.GetProperty("ORIGIN_TOPIC") != "order" || msg.GetProperty("RECONSUME_TIMES") != "2" || msg.GetProperty("DLQ_REASON") != "failed" {
+			t.Fatalf("dlq message = %v", msg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("message not sent to dlq")
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("handler called %d times, want 3", n)
+	}
+}
+
+// 顺序消费失败时原地重试，后续消息不会越过
+func TestMemoryOrderly(t *testing.T) {
+	b := NewMemory(MemoryOptions{RetryDelay: time.Millisecond})
+	defer b.Close()
+	orderly := true
+	ch := make(chan string, 10)
+	var failed atomic.Bool
+	if err := b.Subscribe(config.Consumer{Topic: "order", Orderly: &orderly, Concurrency: 4, Handler: func(ctx context.Context, msg *primitive.MessageExt) error {
+		if string(msg.Body) == "1" && !failed.Swap(true) {
+			return errors.New("failed")
+		}
+		ch <- string(msg.Body)
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"1", "2", "3"} {
+		if err := b.Publish(context.Background(), primitive.NewMessage("order", []byte(body))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := receive(t, ch, 3)
+	if got[0] != "1" || got[1] != "2" || got[2] != "3" {
+		t.Fatalf("got %v, want [1 2 3]", got)
+	}
+}
+
+// 关闭时取消延时任务并等待消费协程退出，可与重试并发
+func TestMemoryClose(t *testing.T) {
+	b := NewMemory(MemoryOptions{RetryDelay: time.Millisecond})
+	var handling sync.WaitGroup
+	handling.Add(1)
+	var once sync.Once
+	if err := b.Subscribe(config.Consumer{Topic: "order", Concurrency: 4, Handler: func(ctx context.Context, msg *primitive.MessageExt) error {
+		once.Do(handling.Done)
+		return errors.New("retry")
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		_ = b.Publish(context.Background(), primitive.NewMessage("order", []byte("x")))
+	}
+	delayed := primitive.NewMessage("order", []byte("delayed"))
+	delayed.WithDelayTimeLevel(18)
+	if err := b.Publish(context.Background(), delayed); err != nil {
+		t.Fatal(err)
+	}
+	handling.Wait()
+	done := make(chan struct{})
+	go func() {
+		_ = b.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close() did not return")
+	}
+	// 重复关闭
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound 缓存未命中，或命中了空值缓存；加载函数返回该错误时写入空值缓存
+var ErrNotFound = errors.New("cache: not found")
+
+// DefaultPrefix 缓存键的默认前缀
+const DefaultPrefix = "hera:cache:"
+
+// 缓存值的首字节，区分正常值与空值
+const (
+	markValue    = 'v'
+	markNegative = 'n'
+)
+
+// Options 缓存选项
+type Options struct {
+	Prefix      string        // 缓存键前缀，默认 hera:cache:
+	TTL         time.Duration // 默认有效期，默认 10 分钟
+	Jitter      float64       // 有效期的随机浮动比例，取值 0~1，避免大量缓存同时过期，0 表示不浮动，小于 0 时使用默认值 0.1
+	NegativeTTL time.Duration // 空值缓存的有效期，默认 1 分钟，小于 0 时不缓存空值
+	LocalSize   int           // 本地缓存的最大条目数，大于 0 时启用二级缓存，各实例之间通过 Redis 发布订阅失效
+	LocalTTL    time.Duration // 本地缓存的有效期，默认 1 分钟
+	LoadTimeout time.Duration // GetOrLoad 中加载函数的超时时间，默认 10 秒
+}
+
+// Cache 基于 Redis 的缓存，值以 JSON 编码。
+// 启用本地缓存时，Set 与 Delete 通过发布订阅通知所有实例删除本地副本。
+type Cache struct {
+	rdb   redis.UniversalClient
+	opts  Options
+	group singleflight.Group
+	local *lru
+	id    string // 实例标识，忽略自己发布的失效通知
+	sub   *redis.PubSub
+	done  chan struct{}
+}
+
+// New 创建缓存，启用本地缓存时订阅失效通知，使用完毕后调用 Close
+func New(rdb redis.UniversalClient, opts Options) *Cache {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 10 * time.Minute
+	}
+	if opts.Jitter < 0 {
+		opts.Jitter = 0.1
+	}
+	if opts.NegativeTTL == 0 {
+		opts.NegativeTTL = time.Minute
+	}
+	if opts.LocalTTL <= 0 {
+		opts.LocalTTL = time.Minute
+	}
+	if opts.LoadTimeout <= 0 {
+		opts.LoadTimeout = 10 * time.Second
+	}
+	c := &Cache{rdb: rdb, opts: opts, id: newId(), done: make(chan struct{})}
+	if opts.LocalSize > 0 {
+		c.local = newLru(opts.LocalSize)
+		c.sub = rdb.Subscribe(context.Background(), c.channel())
+		go c.invalidate()
+	} else {
+		close(c.done)
+	}
+	return c
+}
+
+// Close 停止订阅失效通知，不关闭 Redis 连接
+func (c *Cache) Close() error {
+	if c.sub == nil {
+		return nil
+	}
+	err := c.sub.Close()
+	<-c.done
+	return err
+}
+
+func (c *Cache) key(key string) string {
+	return c.opts.Prefix + key
+}
+
+func (c *Cache) channel() string {
+	return c.opts.Prefix + "invalidate"
+}
+
+// 有效期附加随机浮动
+func (c *Cache) ttl(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		ttl = c.opts.TTL
+	}
+	if c.opts.Jitter > 0 {
+		ttl += time.Duration(mrand.Float64() * c.opts.Jitter * float64(ttl))
+	}
+	return ttl
+}
+
+// 读取编码后的值，本地缓存优先
+func (c *Cache) get(ctx context.Context, key string) ([]byte, error) {
+	if c.local != nil {
+		if b, ok := c.local.get(key); ok {
+			return b, nil
+		}
+	}
+	b, err := c.rdb.Get(ctx, c.key(key)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.local != nil {
+		c.local.set(key, b, c.opts.LocalTTL)
+	}
+	return b, nil
+}
+
+func (c *Cache) set(ctx context.Context, key string, b []byte, ttl time.Duration) error {
+	if err := c.rdb.Set(ctx, c.key(key), b, ttl).Err(); err != nil {
+		return err
+	}
+	c.publish(ctx, key)
+	if c.local != nil {
+		c.local.set(key, b, c.opts.LocalTTL)
+	}
+	return nil
+}
+
+// Delete 删除缓存
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = c.key(key)
+	}
+	if err := c.rdb.Del(ctx, full...).Err(); err != nil {
+		return err
+	}
+	c.publish(ctx, keys...)
+	return nil
+}
+
+// 删除本地副本并通知其他实例，消息格式为 {实例标识}|{键}
+func (c *Cache) publish(ctx context.Context, keys ...string) {
+	if c.local == nil {
+		return
+	}
+	for _, key := range keys {
+		c.local.delete(key)
+		if err := c.rdb.Publish(ctx, c.channel(), c.id+"|"+key).Err(); err != nil {
+			zap.L().Warn("cache invalidation publish failed", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// 接收其他实例的失效通知，连接断开后订阅会自动恢复，期间的通知丢失由本地缓存的有效期兜底
+func (c *Cache) invalidate() {
+	defer close(c.done)
+	for msg := range c.sub.Channel() {
+		id, key, ok := strings.Cut(msg.Payload, "|")
+		if !ok || id == c.id {
+			continue
+		}
+		c.local.delete(key)
+	}
+}
+
+// Get 读取缓存，未命中或命中空值缓存时返回 ErrNotFound
+func Get[T any](ctx context.Context, c *Cache, key string) (T, error) {
+	var v T
+	b, err := c.get(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	return decode[T](b)
+}
+
+// Set 写入缓存，ttl 为 0 时使用默认有效期
+func Set[T any](ctx context.Context, c *Cache, key string, v T, ttl time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cache: encode %s: %w", key, err)
+	}
+	return c.set(ctx, key, append([]byte{markValue}, b...), c.ttl(ttl))
+}
+
+// GetOrLoad 旁路缓存：命中时直接返回，未命中时调用 load 加载并写入缓存。
+// 同一实例内同一个键的并发加载合并为一次，load 收到的 ctx 不随调用方取消，超时由 LoadTimeout 控制；
+// load 返回 ErrNotFound 时写入空值缓存，避免缓存穿透。
+// Redis 不可用时直接调用 load。
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	b, err := c.get(ctx, key)
+	if err == nil {
+		// 命中正常值或空值缓存，无法解码的值重新加载
+		if v, err := decode[T](b); err == nil || errors.Is(err, ErrNotFound) {
+			return v, err
+		}
+	} else if !errors.Is(err, ErrNotFound) {
+		zap.L().Warn("cache get failed", zap.String("key", key), zap.Error(err))
+	}
+	// 合并的加载不受发起者取消的影响，使用保留上下文值的独立上下文与超时，各调用方仍可按自己的 ctx 停止等待
+	ch := c.group.DoChan(key, func() (any, error) {
+		lctx, cancel := context.WithTimeout(detach(ctx), c.opts.LoadTimeout)
+		defer cancel()
+		v, err := load(lctx)
+		if errors.Is(err, ErrNotFound) {
+			if c.opts.NegativeTTL > 0 {
+				if err := c.set(lctx, key, []byte{markNegative}, c.opts.NegativeTTL); err != nil {
+					zap.L().Warn("cache set failed", zap.String("key", key), zap.Error(err))
+				}
+			}
+			return v, ErrNotFound
+		}
+		if err != nil {
+			return v, err
+		}
+		if err := Set(lctx, c, key, v, ttl); err != nil {
+			zap.L().Warn("cache set failed", zap.String("key", key), zap.Error(err))
+		}
+		return v, nil
+	})
+	select {
+	case r := <-ch:
+		v, _ := r.Val.(T)
+		return v, r.Err
+	case <-ctx.Done():
+		var v T
+		return v, ctx.Err()
+	}
+}
+
+// 保留上下文中的值（如追踪ID），但不继承取消与截止时间
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+func decode[T any](b []byte) (T, error) {
+	var v T
+	if len(b) == 0 || b[0] == markNegative {
+		return v, ErrNotFound
+	}
+	if b[0] != markValue {
+		return v, errors.New("cache: unknown value format")
+	}
+	if err := json.Unmarshal(b[1:], &v); err != nil {
+		return v, fmt.Errorf("cache: decode: %w", err)
+	}
+	return v, nil
+}
+
+func newId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
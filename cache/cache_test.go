@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestJitter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	tests := []struct {
+		name     string
+		jitter   float64
+		min, max time.Duration
+	}{
+		{"off", 0, time.Minute, time.Minute},
+		{"default", -1, time.Minute, time.Minute + 6*time.Second},
+		{"custom", 0.5, time.Minute, time.Minute + 30*time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(rdb, Options{TTL: time.Minute, Jitter: tt.jitter})
+			defer c.Close()
+			for i := 0; i < 100; i++ {
+				if ttl := c.ttl(0); ttl < tt.min || ttl > tt.max {
+					t.Fatalf("ttl() = %v, want between %v and %v", ttl, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
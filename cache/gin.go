@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// KeyFunc 生成响应缓存的键，返回空字符串时不缓存
+type KeyFunc func(ctx *gin.Context) string
+
+// URIKey 以请求路径与查询参数作为缓存键
+func URIKey(ctx *gin.Context) string {
+	return ctx.Request.URL.RequestURI()
+}
+
+// 缓存的响应
+type page struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// 记录响应内容的写入器
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// CachePage 缓存 GET 与 HEAD 请求的 200 响应，ttl 为 0 时使用默认有效期。响应头 X-Cache 为 HIT 或 MISS。
+// key 为空时使用 URIKey，并跳过携带 Authorization 或 Cookie 的请求，避免将一个用户的响应返回给其他用户；
+// 需要缓存登录用户的响应时传入包含用户标识的 key。
+func CachePage(c *Cache, ttl time.Duration, key KeyFunc) gin.HandlerFunc {
+	public := key == nil
+	if public {
+		key = URIKey
+	}
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+			ctx.Next()
+			return
+		}
+		if public && (ctx.GetHeader("Authorization") != "" || ctx.GetHeader("Cookie") != "") {
+			ctx.Next()
+			return
+		}
+		k := key(ctx)
+		if k == "" {
+			ctx.Next()
+			return
+		}
+		k = "page:" + k
+		if p, err := Get[page](ctx.Request.Context(), c, k); err == nil {
+			for name, values := range p.Header {
+				for _, v := range values {
+					ctx.Writer.Header().Add(name, v)
+				}
+			}
+			ctx.Header("X-Cache", "HIT")
+			ctx.Data(p.Status, p.Header.Get("Content-Type"), p.Body)
+			ctx.Abort()
+			return
+		}
+		ctx.Header("X-Cache", "MISS")
+		w := &recorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = w
+		ctx.Next()
+		if w.Status() != http.StatusOK || ctx.IsAborted() {
+			return
+		}
+		header := w.Header().Clone()
+		header.Del("X-Cache")
+		header.Del("Set-Cookie")
+		p := page{Status: w.Status(), Header: header, Body: w.body.Bytes()}
+		// 请求结束后上下文可能已取消，写入缓存使用独立的超时
+		sctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := Set(sctx, c, k, p, ttl); err != nil {
+			zap.L().Warn("cache page failed", zap.String("key", k), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// 带有效期的本地LRU缓存，并发安全
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	items map[string]*list.Element
+	order *list.List // 最近使用的在前
+}
+
+type entry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+func newLru(size int) *lru {
+	return &lru{size: size, items: make(map[string]*list.Element, size), order: list.New()}
+}
+
+func (l *lru) get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.Value.(*entry).expireAt) {
+		l.remove(e)
+		return nil, false
+	}
+	l.order.MoveToFront(e)
+	return e.Value.(*entry).value, true
+}
+
+func (l *lru) set(key string, value []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.items[key]; ok {
+		e.Value = &entry{key: key, value: value, expireAt: time.Now().Add(ttl)}
+		l.order.MoveToFront(e)
+		return
+	}
+	l.items[key] = l.order.PushFront(&entry{key: key, value: value, expireAt: time.Now().Add(ttl)})
+	if l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+}
+
+func (l *lru) delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.items[key]; ok {
+		l.remove(e)
+	}
+}
+
+func (l *lru) remove(e *list.Element) {
+	l.order.Remove(e)
+	delete(l.items, e.Value.(*entry).key)
+}
@@ -5,4 +5,6 @@ type App struct {
 	Port    string `mapstructure:"port" json:"port" yaml:"port"`
 	AppName string `mapstructure:"app_name" json:"app_name" yaml:"app_name"`
 	AppUrl  string `mapstructure:"app_url" json:"app_url" yaml:"app_url"`
+	// 优雅关闭时每个阶段的排空超时时间（秒），默认 5 秒
+	ShutdownTimeout int `mapstructure:"shutdown_timeout" json:"shutdown_timeout" yaml:"shutdown_timeout"`
 }
@@ -0,0 +1,10 @@
+package config
+
+type Cache struct {
+	Prefix      string   `mapstructure:"prefix" json:"prefix" yaml:"prefix"`                   // 缓存键前缀，默认 hera:cache:
+	Ttl         int      `mapstructure:"ttl" json:"ttl" yaml:"ttl"`                            // 默认有效期，单位秒，默认600
+	Jitter      *float64 `mapstructure:"jitter" json:"jitter" yaml:"jitter"`                   // 有效期的随机浮动比例，取值0~1，默认0.1，0 表示不浮动
+	NegativeTtl int      `mapstructure:"negative_ttl" json:"negative_ttl" yaml:"negative_ttl"` // 空值缓存的有效期，单位秒，默认60，-1 表示不缓存空值
+	LocalSize   int      `mapstructure:"local_size" json:"local_size" yaml:"local_size"`       // 本地二级缓存的最大条目数，0 表示不启用
+	LocalTtl    int      `mapstructure:"local_ttl" json:"local_ttl" yaml:"local_ttl"`          // 本地缓存的有效期，单位秒，默认60
+}
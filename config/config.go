@@ -1,15 +1,24 @@
 package config
 
 type Configuration struct {
-	App            App      `mapstructure:"app" json:"app" yaml:"app"`
-	Log            Log      `mapstructure:"log" json:"log" yaml:"log"`
-	Database       Database `mapstructure:"database" json:"database" yaml:"database"`
-	Redis          Redis    `mapstructure:"redis" json:"redis" yaml:"redis"`
-	Jwt            Jwt      `mapstructure:"jwt" json:"jwt" yaml:"jwt"`
-	Xxl            Xxl      `mapstructure:"xxl" json:"xxl" yaml:"xxl"`
-	Nacos          Nacos    `mapstructure:"nacos" json:"nacos" yaml:"nacos"`
-	Rokcetmq       Rokcetmq `mapstructure:"rokcetmq" json:"rokcetmq" yaml:"rokcetmq"`
-	Oss            Oss      `mapstructure:"oss" json:"oss" yaml:"oss"`
+	App            App                 `mapstructure:"app" json:"app" yaml:"app"`
+	Log            Log                 `mapstructure:"log" json:"log" yaml:"log"`
+	Database       Database            `mapstructure:"database" json:"database" yaml:"database"`
+	Databases      map[string]Database `mapstructure:"databases" json:"databases" yaml:"databases"` // 具名的数据库连接，通过 global.DB(name) 获取
+	Redis          Redis               `mapstructure:"redis" json:"redis" yaml:"redis"`
+	RedisInstances map[string]Redis    `mapstructure:"redis_instances" json:"redis_instances" yaml:"redis_instances"` // 具名的 Redis 实例，通过 global.Redis(name) 获取
+	Jwt            Jwt                 `mapstructure:"jwt" json:"jwt" yaml:"jwt"`
+	Xxl            Xxl                 `mapstructure:"xxl" json:"xxl" yaml:"xxl"`
+	Nacos          Nacos               `mapstructure:"nacos" json:"nacos" yaml:"nacos"`
+	Rokcetmq       Rokcetmq            `mapstructure:"rokcetmq" json:"rokcetmq" yaml:"rokcetmq"`
+	Oss            Oss                 `mapstructure:"oss" json:"oss" yaml:"oss"`
+	Ports          Ports               `mapstructure:"ports" json:"ports" yaml:"ports"`
+	Ws             Ws                  `mapstructure:"ws" json:"ws" yaml:"ws"`
+	Outbox         Outbox              `mapstructure:"outbox" json:"outbox" yaml:"outbox"`
+	Migrate        Migrate             `mapstructure:"migrate" json:"migrate" yaml:"migrate"`
+	Metrics        Metrics             `mapstructure:"metrics" json:"metrics" yaml:"metrics"`
+	Lock           Lock                `mapstructure:"lock" json:"lock" yaml:"lock"`
+	Cache          Cache               `mapstructure:"cache" json:"cache" yaml:"cache"`
 	UpdateVersion  UpdateVersion
 	StartUpIos     StartUpIos
 	StartUpAndroid StartUpAndroid
@@ -1,16 +1,37 @@
 package config
 
+// 数据库驱动
+const (
+	DriverMysql    = "mysql"    // 默认
+	DriverPostgres = "postgres" // PostgreSQL
+	DriverSqlite   = "sqlite"   // SQLite，database 为文件路径，:memory: 为内存数据库，无需数据库服务即可运行测试
+)
+
 type Database struct {
-	Driver              string `mapstructure:"driver" json:"driver" yaml:"driver"`
-	Host                string `mapstructure:"host" json:"host" yaml:"host"`
-	Port                int    `mapstructure:"port" json:"port" yaml:"port"`
-	Database            string `mapstructure:"database" json:"database" yaml:"database"`
-	UserName            string `mapstructure:"username" json:"username" yaml:"username"`
-	Password            string `mapstructure:"password" json:"password" yaml:"password"`
-	Charset             string `mapstructure:"charset" json:"charset" yaml:"charset"`
-	MaxIdleConns        int    `mapstructure:"max_idle_conns" json:"max_idle_conns" yaml:"max_idle_conns"`
-	MaxOpenConns        int    `mapstructure:"max_open_conns" json:"max_open_conns" yaml:"max_open_conns"`
-	LogMode             string `mapstructure:"log_mode" json:"log_mode" yaml:"log_mode"`
-	EnableFileLogWriter bool   `mapstructure:"enable_file_log_writer" json:"enable_file_log_writer" yaml:"enable_file_log_writer"`
-	LogFilename         string `mapstructure:"log_filename" json:"log_filename" yaml:"log_filename"`
+	Driver              string            `mapstructure:"driver" json:"driver" yaml:"driver"` // mysql、postgres 或 sqlite
+	Host                string            `mapstructure:"host" json:"host" yaml:"host"`
+	Port                int               `mapstructure:"port" json:"port" yaml:"port"`
+	Database            string            `mapstructure:"database" json:"database" yaml:"database"`
+	UserName            string            `mapstructure:"username" json:"username" yaml:"username"`
+	Password            Secret            `mapstructure:"password" json:"password" yaml:"password"`
+	Charset             string            `mapstructure:"charset" json:"charset" yaml:"charset"`
+	Dsn                 Secret            `mapstructure:"dsn" json:"dsn" yaml:"dsn"`                // 完整的连接串，配置后忽略 host、port 等连接参数
+	Replicas            []DatabaseReplica `mapstructure:"replicas" json:"replicas" yaml:"replicas"` // 只读副本，查询语句自动路由到副本，事务与写入使用主库
+	MaxIdleConns        int               `mapstructure:"max_idle_conns" json:"max_idle_conns" yaml:"max_idle_conns"`
+	MaxOpenConns        int               `mapstructure:"max_open_conns" json:"max_open_conns" yaml:"max_open_conns"`
+	ConnMaxLifetime     int               `mapstructure:"conn_max_lifetime" json:"conn_max_lifetime" yaml:"conn_max_lifetime"`    // 连接最长使用时间（秒），0 表示不限制
+	ConnMaxIdleTime     int               `mapstructure:"conn_max_idle_time" json:"conn_max_idle_time" yaml:"conn_max_idle_time"` // 连接最长空闲时间（秒），0 表示不限制
+	LogMode             string            `mapstructure:"log_mode" json:"log_mode" yaml:"log_mode"`                               // silent、error、warn 或 info（默认）
+	SlowThreshold       int               `mapstructure:"slow_threshold" json:"slow_threshold" yaml:"slow_threshold"`             // 慢查询阈值（毫秒），默认 200，负数表示不记录慢查询
+	EnableFileLogWriter bool              `mapstructure:"enable_file_log_writer" json:"enable_file_log_writer" yaml:"enable_file_log_writer"`
+	LogFilename         string            `mapstructure:"log_filename" json:"log_filename" yaml:"log_filename"`
+}
+
+// DatabaseReplica 只读副本，未配置的端口、账号与密码与主库相同
+type DatabaseReplica struct {
+	Host     string `mapstructure:"host" json:"host" yaml:"host"`
+	Port     int    `mapstructure:"port" json:"port" yaml:"port"`
+	UserName string `mapstructure:"username" json:"username" yaml:"username"`
+	Password Secret `mapstructure:"password" json:"password" yaml:"password"`
+	Dsn      Secret `mapstructure:"dsn" json:"dsn" yaml:"dsn"` // 完整的连接串，配置后忽略其他参数
 }
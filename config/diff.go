@@ -0,0 +1,83 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Change 配置中一个字段的变更，Path 为配置文件中的键路径，如 log.level
+type Change struct {
+	Path string
+	Old  any
+	New  any
+}
+
+// Diff 逐字段比较两份配置，切片与映射作为整体比较，函数字段被忽略
+func Diff(old, new *Configuration) []Change {
+	var changes []Change
+	if old == nil || new == nil {
+		return changes
+	}
+	diff("", reflect.ValueOf(*old), reflect.ValueOf(*new), &changes)
+	return changes
+}
+
+// DiffValues 逐字段比较两个同类型的值，用于业务配置，键路径规则与 Diff 相同
+func DiffValues(old, new any) []Change {
+	var changes []Change
+	ov, nv := reflect.Indirect(reflect.ValueOf(old)), reflect.Indirect(reflect.ValueOf(new))
+	if !ov.IsValid() || !nv.IsValid() || ov.Type() != nv.Type() {
+		return changes
+	}
+	diff("", ov, nv, &changes)
+	return changes
+}
+
+// Changed 变更中是否包含指定的键路径或其子路径
+func Changed(changes []Change, paths ...string) bool {
+	for _, c := range changes {
+		for _, p := range paths {
+			if c.Path == p || strings.HasPrefix(c.Path, p+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func diff(path string, old, new reflect.Value, changes *[]Change) {
+	if old.Kind() == reflect.Struct {
+		t := old.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Type.Kind() == reflect.Func {
+				continue
+			}
+			// 嵌入的结构体字段与外层处于同一层级
+			p := path
+			if !f.Anonymous {
+				p = join(path, key(f))
+			}
+			diff(p, old.Field(i), new.Field(i), changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+		*changes = append(*changes, Change{Path: path, Old: old.Interface(), New: new.Interface()})
+	}
+}
+
+// 字段在配置文件中的键，与 mapstructure 的匹配规则一致
+func key(f reflect.StructField) string {
+	if tag := strings.Split(f.Tag.Get("mapstructure"), ",")[0]; tag != "" && tag != "-" {
+		return tag
+	}
+	return strings.ToLower(f.Name)
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
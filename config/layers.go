@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// 配置来源，按优先级从低到高排列，后面的来源覆盖前面的来源
+const (
+	SourceDefault = "default" // 内置默认值
+	SourceFile    = "file"    // config.yaml，路径可由 VIPER_CONFIG 指定
+	SourceOverlay = "overlay" // 与环境对应的覆盖文件，如 config.release.yaml
+	SourceEnv     = "env"     // 环境变量，如 HERA_DATABASE_HOST
+	SourceNacos   = "nacos"   // Nacos 中的 {app_name}-{env}.yaml
+	SourceFlag    = "flag"    // 命令行参数
+)
+
+// Sources 所有配置来源，按优先级从低到高排列
+var Sources = []string{SourceDefault, SourceFile, SourceOverlay, SourceEnv, SourceNacos, SourceFlag}
+
+// EnvPrefix 环境变量前缀，database.host 对应 HERA_DATABASE_HOST
+const EnvPrefix = "HERA"
+
+// Defaults 内置默认值
+func Defaults() map[string]any {
+	return map[string]any{
+		"app.env":      "debug",
+		"log.level":    "info",
+		"log.root_dir": "logs",
+	}
+}
+
+// EnvName 配置键对应的环境变量名
+func EnvName(path string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// Values 按键路径展开配置中的所有字段，切片与映射作为整体，函数字段被忽略
+func Values(c *Configuration) map[string]any {
+	values := make(map[string]any)
+	if c == nil {
+		return values
+	}
+	walk("", reflect.ValueOf(*c), func(path string, v reflect.Value) {
+		values[path] = v.Interface()
+	})
+	return values
+}
+
+// Flatten 将嵌套的映射展开为以点分隔的键路径，键统一转为小写
+func Flatten(m map[string]any) map[string]any {
+	out := make(map[string]any)
+	flatten("", m, out)
+	return out
+}
+
+func flatten(path string, v any, out map[string]any) {
+	switch m := v.(type) {
+	case map[string]any:
+		for k, v := range m {
+			flatten(join(path, strings.ToLower(k)), v, out)
+		}
+	case map[any]any:
+		for k, v := range m {
+			if s, ok := k.(string); ok {
+				flatten(join(path, strings.ToLower(s)), v, out)
+			}
+		}
+	default:
+		if path != "" {
+			out[path] = v
+		}
+	}
+}
+
+func walk(path string, v reflect.Value, fn func(path string, v reflect.Value)) {
+	if v.Kind() != reflect.Struct {
+		fn(path, v)
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Type.Kind() == reflect.Func {
+			continue
+		}
+		// 嵌入的结构体字段与外层处于同一层级
+		p := path
+		if !f.Anonymous {
+			p = join(path, key(f))
+		}
+		walk(p, v.Field(i), fn)
+	}
+}
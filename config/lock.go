@@ -0,0 +1,8 @@
+package config
+
+type Lock struct {
+	Prefix        string   `mapstructure:"prefix" json:"prefix" yaml:"prefix"`                         // 锁键前缀，默认 hera:lock:
+	Ttl           int      `mapstructure:"ttl" json:"ttl" yaml:"ttl"`                                  // 锁的有效期，单位毫秒，默认30000，持有期间自动续期
+	RetryInterval int      `mapstructure:"retry_interval" json:"retry_interval" yaml:"retry_interval"` // 等待锁时的重试间隔，单位毫秒，默认100
+	Redlock       []string `mapstructure:"redlock" json:"redlock" yaml:"redlock"`                      // redis_instances 中的实例名称，配置后在这些相互独立的实例上使用 Redlock 算法
+}
@@ -0,0 +1,7 @@
+package config
+
+type Metrics struct {
+	Enable   bool   `mapstructure:"enable" json:"enable" yaml:"enable"`       // 是否开启 Prometheus 指标
+	Path     string `mapstructure:"path" json:"path" yaml:"path"`             // HTTP 服务上的指标路径，默认 /metrics
+	Interval int    `mapstructure:"interval" json:"interval" yaml:"interval"` // 连接池指标的采集间隔（秒），默认 15
+}
@@ -0,0 +1,7 @@
+package config
+
+type Migrate struct {
+	Auto        bool   `mapstructure:"auto" json:"auto" yaml:"auto"`                         // 启动时自动执行未执行的迁移，默认关闭
+	Table       string `mapstructure:"table" json:"table" yaml:"table"`                      // 迁移记录表，默认 schema_migrations
+	LockTimeout int    `mapstructure:"lock_timeout" json:"lock_timeout" yaml:"lock_timeout"` // 等待其他实例完成迁移的最长时间（秒），默认 60
+}
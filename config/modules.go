@@ -14,6 +14,7 @@ type AllModules struct {
 	Xxl      bool
 	Metadata bool
 	Rocketmq bool
+	Outbox   bool
 	Swagger  bool
 	Grpc     bool
 	Ws       bool
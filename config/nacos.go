@@ -4,8 +4,21 @@ type Nacos struct {
 	Servers   []Server `mapstructure:"servers" json:"servers" yaml:"servers"`
 	Namespace string   `mapstructure:"namespace" json:"namespace" yaml:"namespace"`
 	Username  string   `mapstructure:"username" json:"username" yaml:"username"`
-	Password  string   `mapstructure:"password" json:"password" yaml:"password"`
+	Password  Secret   `mapstructure:"password" json:"password" yaml:"password"`
 	DataId    string   `mapstructure:"data-id" json:"data-id" yaml:"data-id"`
+	// Discovery 服务注册与发现
+	Discovery NacosDiscovery `mapstructure:"discovery" json:"discovery" yaml:"discovery"`
+}
+
+// NacosDiscovery 服务注册配置，gRPC 客户端可通过 nacos:///{service} 发现已注册的服务
+type NacosDiscovery struct {
+	Register bool              `mapstructure:"register" json:"register" yaml:"register"` // 启动后注册为服务实例，关闭时注销
+	Service  string            `mapstructure:"service" json:"service" yaml:"service"`    // 服务名，默认 app.app_name
+	Group    string            `mapstructure:"group" json:"group" yaml:"group"`          // 服务分组，默认 DEFAULT_GROUP
+	Cluster  string            `mapstructure:"cluster" json:"cluster" yaml:"cluster"`    // 集群名，默认 DEFAULT
+	Ip       string            `mapstructure:"ip" json:"ip" yaml:"ip"`                   // 注册的IP，默认取本机第一个非回环的IPv4地址
+	Weight   float64           `mapstructure:"weight" json:"weight" yaml:"weight"`       // 权重，默认 1
+	Metadata map[string]string `mapstructure:"metadata" json:"metadata" yaml:"metadata"` // 附加的元数据
 }
 
 type Server struct {
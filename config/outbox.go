@@ -0,0 +1,9 @@
+package config
+
+type Outbox struct {
+	Interval    int `mapstructure:"interval" json:"interval" yaml:"interval"`             // 轮询间隔，单位毫秒，默认1000
+	Batch       int `mapstructure:"batch" json:"batch" yaml:"batch"`                      // 每轮发布的最大条数，默认100
+	MaxAttempts int `mapstructure:"max_attempts" json:"max_attempts" yaml:"max_attempts"` // 最大发布次数，默认10，超过后标记为失败
+	Backoff     int `mapstructure:"backoff" json:"backoff" yaml:"backoff"`                // 发布失败后的初始退避，单位毫秒，默认1000，每次失败翻倍
+	Retention   int `mapstructure:"retention" json:"retention" yaml:"retention"`          // 已发布消息的保留时长，单位小时，默认72
+}
@@ -0,0 +1,13 @@
+package config
+
+// 端口布局
+const (
+	PortsModeMux      = "mux"      // 所有协议共用 App.Port，由 cmux 按协议分发
+	PortsModeSeparate = "separate" // 各协议使用独立端口
+)
+
+type Ports struct {
+	Mode     string `mapstructure:"mode" json:"mode" yaml:"mode"`                // mux（默认）或 separate
+	GrpcPort string `mapstructure:"grpc_port" json:"grpc_port" yaml:"grpc_port"` // separate 模式下的 gRPC 端口，默认 App.Port+10000
+	WsPort   string `mapstructure:"ws_port" json:"ws_port" yaml:"ws_port"`       // separate 模式下的 WebSocket 端口，为空时通过 HTTP 路由 /ws 提供
+}
@@ -1,8 +1,39 @@
 package config
 
+// Redis 部署模式
+const (
+	RedisStandalone = "standalone" // 单节点，默认
+	RedisSentinel   = "sentinel"   // 哨兵，addrs 为哨兵地址
+	RedisCluster    = "cluster"    // 集群，addrs 为节点地址
+)
+
 type Redis struct {
-	Host     string `mapstructure:"host" json:"host" yaml:"host"`
-	Port     string `mapstructure:"port" json:"port" yaml:"port"`
-	DB       int    `mapstructure:"db" json:"db" yaml:"db"`
-	Password string `mapstructure:"password" json:"password" yaml:"password"`
+	Mode             string   `mapstructure:"mode" json:"mode" yaml:"mode"` // standalone、sentinel 或 cluster
+	Host             string   `mapstructure:"host" json:"host" yaml:"host"`
+	Port             string   `mapstructure:"port" json:"port" yaml:"port"`
+	Addrs            []string `mapstructure:"addrs" json:"addrs" yaml:"addrs"`                   // 哨兵或集群节点地址 host:port，单节点模式下为空时使用 host:port
+	MasterName       string   `mapstructure:"master_name" json:"master_name" yaml:"master_name"` // 哨兵模式下的主节点名称
+	DB               int      `mapstructure:"db" json:"db" yaml:"db"`                            // 集群模式下不支持
+	Username         string   `mapstructure:"username" json:"username" yaml:"username"`
+	Password         Secret   `mapstructure:"password" json:"password" yaml:"password"`
+	SentinelPassword Secret   `mapstructure:"sentinel_password" json:"sentinel_password" yaml:"sentinel_password"`
+	PoolSize         int      `mapstructure:"pool_size" json:"pool_size" yaml:"pool_size"`                   // 连接池大小，默认每个CPU 10个连接
+	MinIdleConns     int      `mapstructure:"min_idle_conns" json:"min_idle_conns" yaml:"min_idle_conns"`    // 最少空闲连接数
+	DialTimeout      int      `mapstructure:"dial_timeout" json:"dial_timeout" yaml:"dial_timeout"`          // 建立连接超时（毫秒），默认 5000
+	ReadTimeout      int      `mapstructure:"read_timeout" json:"read_timeout" yaml:"read_timeout"`          // 读超时（毫秒），默认 3000
+	WriteTimeout     int      `mapstructure:"write_timeout" json:"write_timeout" yaml:"write_timeout"`       // 写超时（毫秒），默认与读超时相同
+	PoolTimeout      int      `mapstructure:"pool_timeout" json:"pool_timeout" yaml:"pool_timeout"`          // 等待空闲连接超时（毫秒），默认为读超时加 1 秒
+	MaxRetries       int      `mapstructure:"max_retries" json:"max_retries" yaml:"max_retries"`             // 命令失败的重试次数，默认 3，-1 表示不重试
+	ConnectRetries   int      `mapstructure:"connect_retries" json:"connect_retries" yaml:"connect_retries"` // 启动时连接失败的重试次数，默认 3，-1 表示不重试，每次等待时间翻倍
+	Tls              RedisTls `mapstructure:"tls" json:"tls" yaml:"tls"`
+}
+
+// RedisTls TLS 连接配置
+type RedisTls struct {
+	Enable             bool   `mapstructure:"enable" json:"enable" yaml:"enable"`
+	ServerName         string `mapstructure:"server_name" json:"server_name" yaml:"server_name"`
+	CaFile             string `mapstructure:"ca_file" json:"ca_file" yaml:"ca_file"`       // 校验服务端证书的 CA，为空时使用系统根证书
+	CertFile           string `mapstructure:"cert_file" json:"cert_file" yaml:"cert_file"` // 客户端证书，双向认证时配置
+	KeyFile            string `mapstructure:"key_file" json:"key_file" yaml:"key_file"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify" json:"insecure_skip_verify" yaml:"insecure_skip_verify"` // 不校验服务端证书，仅用于测试
 }
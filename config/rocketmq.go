@@ -1,11 +1,46 @@
 package config
 
+import (
+	"context"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+)
+
+// 消息代理类型
+const (
+	BrokerRocketmq = "rocketmq" // RocketMQ，默认
+	BrokerMemory   = "memory"   // 进程内通道，用于测试与本地开发
+)
+
 type Rokcetmq struct {
-	Addr      string     `mapstructure:"addr" json:"addr" yaml:"addr"`
-	Consumers []Consumer ` yaml:"consumers"`
+	Broker      string     `mapstructure:"broker" json:"broker" yaml:"broker"` // 消息代理类型：rocketmq、memory
+	Addr        string     `mapstructure:"addr" json:"addr" yaml:"addr"`
+	Consumers   []Consumer `mapstructure:"consumers" json:"consumers" yaml:"consumers"`       // 声明式的消费者，处理函数按名称引用代码中注册的函数
+	MaxRetries  int32      `mapstructure:"max_retries" json:"max_retries" yaml:"max_retries"` // 消费失败的最大重试次数，默认16，超过后投递到死信主题
+	DlqSuffix   string     `mapstructure:"dlq_suffix" json:"dlq_suffix" yaml:"dlq_suffix"`    // 死信主题为原主题加后缀，默认 _DLQ
+	Concurrency int        `mapstructure:"concurrency" json:"concurrency" yaml:"concurrency"` // 每个消费者的最大并发数，默认20
+	Orderly     bool       `mapstructure:"orderly" json:"orderly" yaml:"orderly"`             // 顺序消费，同一队列的消息逐条处理
 }
 
+// 消费起始位置
+const (
+	ConsumeFromLast      = "last"      // 从最新的消息开始，默认
+	ConsumeFromFirst     = "first"     // 从最早的消息开始
+	ConsumeFromTimestamp = "timestamp" // 从指定时间开始
+)
+
+// Consumer 消费者声明，每个消费者使用独立的消费组，同一主题可以有多个消费者
 type Consumer struct {
-	Topic string `mapstructure:"topic" json:"topic" yaml:"topic"`
-	Func  func() `mapstructure:"func" json:"func" yaml:"func"`
+	Topic        string `mapstructure:"topic" json:"topic" yaml:"topic"`
+	Tag          string `mapstructure:"tag" json:"tag" yaml:"tag"`                            // 标签表达式，如 TagA || TagB
+	Sql          string `mapstructure:"sql" json:"sql" yaml:"sql"`                            // SQL92 过滤表达式，与 tag 二选一
+	Group        string `mapstructure:"group" json:"group" yaml:"group"`                      // 消费组，默认 {topic}-{app_name}-{gin_mode}，同一主题有多个消费者时附加标签、SQL 摘要或处理函数名称
+	Broadcasting bool   `mapstructure:"broadcasting" json:"broadcasting" yaml:"broadcasting"` // 广播消费，默认集群消费
+	Orderly      *bool  `mapstructure:"orderly" json:"orderly" yaml:"orderly"`                // 顺序消费，默认使用 rocketmq.orderly
+	FromWhere    string `mapstructure:"from_where" json:"from_where" yaml:"from_where"`       // 消费起始位置：last、first、timestamp
+	Timestamp    string `mapstructure:"timestamp" json:"timestamp" yaml:"timestamp"`          // from_where 为 timestamp 时的起始时间，格式 yyyyMMddHHmmss
+	Concurrency  int    `mapstructure:"concurrency" json:"concurrency" yaml:"concurrency"`    // 最大并发数，默认使用 rocketmq.concurrency
+	HandlerName  string `mapstructure:"handler" json:"handler" yaml:"handler"`                // 处理函数名称，配置文件中声明时必填，代码中声明时默认为函数名
+
+	// Handler 处理函数，在代码中声明时直接指定
+	Handler func(ctx context.Context, msg *primitive.MessageExt) error `mapstructure:"-" json:"-" yaml:"-"`
 }
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// SecretKeyEnv 解密 ENC(...) 配置值的本地密钥所在的环境变量
+const SecretKeyEnv = "HERA_SECRET_KEY"
+
+const redacted = "******"
+
+// Secret 敏感配置值，在日志、JSON 与 fmt 输出中以 ****** 代替，明文通过 Value 获取
+//
+// 配置中的值可以是明文，也可以是引用：
+//   - ${env:NAME} 读取环境变量
+//   - ${file:/path} 读取文件内容，忽略末尾的换行
+//   - ENC(...) 使用 HERA_SECRET_KEY 解密，密文由 EncryptSecret 生成
+type Secret string
+
+// Value 明文
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Resolve 解析引用得到明文，不是引用时原样返回
+func (s Secret) Resolve() (Secret, error) {
+	v := string(s)
+	switch {
+	case strings.HasPrefix(v, "${env:") && strings.HasSuffix(v, "}"):
+		name := v[len("${env:") : len(v)-1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret env %s is not set", name)
+		}
+		return Secret(value), nil
+	case strings.HasPrefix(v, "${file:") && strings.HasSuffix(v, "}"):
+		b, err := os.ReadFile(v[len("${file:") : len(v)-1])
+		if err != nil {
+			return "", err
+		}
+		return Secret(strings.TrimRight(string(b), "\r\n")), nil
+	case strings.HasPrefix(v, "ENC(") && strings.HasSuffix(v, ")"):
+		plain, err := decrypt(v[len("ENC("):len(v)-1], os.Getenv(SecretKeyEnv))
+		if err != nil {
+			return "", err
+		}
+		return Secret(plain), nil
+	}
+	return s, nil
+}
+
+// EncryptSecret 使用本地密钥加密明文，返回可直接写入配置的 ENC(...) 形式
+func EncryptSecret(plain, key string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return "ENC(" + base64.StdEncoding.EncodeToString(sealed) + ")", nil
+}
+
+func decrypt(text, key string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted secret: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted secret")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("decrypt secret failed, check " + SecretKeyEnv)
+	}
+	return string(plain), nil
+}
+
+// AES-256-GCM，密钥由本地密钥的 SHA-256 摘要得到
+func newGCM(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New(SecretKeyEnv + " is not set")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ResolveSecrets 解析配置中所有 Secret 字段的引用
+func ResolveSecrets(c *Configuration) error {
+	return resolve("", reflect.ValueOf(c).Elem())
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+func resolve(path string, v reflect.Value) error {
+	switch {
+	case v.Type() == secretType:
+		s, err := v.Interface().(Secret).Resolve()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.Set(reflect.ValueOf(s))
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			p := path
+			if !f.Anonymous {
+				p = join(path, key(f))
+			}
+			if err := resolve(p, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case v.Kind() == reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolve(fmt.Sprintf("%s[%d]", path, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case v.Kind() == reflect.Map && v.Type().Elem() == secretType:
+		for _, k := range v.MapKeys() {
+			s, err := v.MapIndex(k).Interface().(Secret).Resolve()
+			if err != nil {
+				return fmt.Errorf("%s.%v: %w", path, k, err)
+			}
+			v.SetMapIndex(k, reflect.ValueOf(s))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,398 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Problem 一项配置问题，Path 为配置文件中的键路径
+type Problem struct {
+	Path    string
+	Message string
+}
+
+// ValidationError 配置校验失败，包含发现的所有问题
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration, %d problem(s):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  - %s: %s", p.Path, p.Message)
+	}
+	return b.String()
+}
+
+// 收集校验问题
+type report struct {
+	problems []Problem
+}
+
+func (r *report) add(path, format string, args ...any) {
+	r.problems = append(r.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// 合并各部分的校验结果
+func (r *report) merge(err error) {
+	var v *ValidationError
+	if errors.As(err, &v) {
+		r.problems = append(r.problems, v.Problems...)
+	} else if err != nil {
+		r.add("", "%s", err)
+	}
+}
+
+func (r *report) err() error {
+	if len(r.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: r.problems}
+}
+
+func (r *report) required(path, v string) {
+	if strings.TrimSpace(v) == "" {
+		r.add(path, "is required")
+	}
+}
+
+func (r *report) port(path, v string) {
+	if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 65535 {
+		r.add(path, "must be a port number between 1 and 65535, got %q", v)
+	}
+}
+
+func (r *report) oneOf(path, v string, allowed ...string) {
+	for _, a := range allowed {
+		if v == a {
+			return
+		}
+	}
+	r.add(path, "must be one of [%s], got %q", strings.Join(allowed, ", "), v)
+}
+
+func (r *report) nonNegative(path string, v int64) {
+	if v < 0 {
+		r.add(path, "must not be negative, got %d", v)
+	}
+}
+
+// Validate 按启用的模块校验配置，启动时与每次热更新时调用，返回的错误为包含所有问题的 *ValidationError
+func (c *Configuration) Validate(modules *AllModules) error {
+	if modules == nil {
+		modules = new(AllModules)
+	}
+	r := new(report)
+	r.merge(c.App.Validate())
+	r.merge(c.Log.Validate())
+	r.merge(c.Ports.Validate())
+	r.nonNegative("metrics.interval", int64(c.Metrics.Interval))
+	if c.Metrics.Path != "" && !strings.HasPrefix(c.Metrics.Path, "/") {
+		r.add("metrics.path", "must start with /, got %q", c.Metrics.Path)
+	}
+	// 提供 HTTP 或 WebSocket 服务，或 gRPC 未单独配置端口时需要 app.port
+	if c.App.Port == "" && (modules.Http || modules.Ws || modules.Grpc && c.Ports.GrpcPort == "") {
+		r.add("app.port", "is required to serve http, ws or grpc")
+	}
+	if modules.Db {
+		r.nonNegative("migrate.lock_timeout", int64(c.Migrate.LockTimeout))
+		r.merge(c.Database.Validate())
+		for _, name := range sortedKeys(c.Databases) {
+			r.merge(c.Databases[name].validate("databases." + name))
+		}
+	}
+	if modules.Redis {
+		r.merge(c.Redis.Validate())
+		for _, name := range sortedKeys(c.RedisInstances) {
+			r.merge(c.RedisInstances[name].validate("redis_instances." + name))
+		}
+		r.nonNegative("lock.ttl", int64(c.Lock.Ttl))
+		r.nonNegative("lock.retry_interval", int64(c.Lock.RetryInterval))
+		r.nonNegative("cache.ttl", int64(c.Cache.Ttl))
+		r.nonNegative("cache.local_size", int64(c.Cache.LocalSize))
+		r.nonNegative("cache.local_ttl", int64(c.Cache.LocalTtl))
+		if j := c.Cache.Jitter; j != nil && (*j < 0 || *j > 1) {
+			r.add("cache.jitter", "must be between 0 and 1, got %v", *j)
+		}
+		for i, name := range c.Lock.Redlock {
+			if _, ok := c.RedisInstances[strings.ToLower(name)]; !ok {
+				r.add(fmt.Sprintf("lock.redlock[%d]", i), "unknown redis instance %q", name)
+			}
+		}
+	}
+	if modules.Nacos {
+		r.merge(c.Nacos.Validate())
+	}
+	if modules.Oss {
+		r.merge(c.Oss.Validate())
+	}
+	if modules.Xxl {
+		r.merge(c.Xxl.Validate())
+	}
+	if modules.Rocketmq {
+		r.merge(c.Rokcetmq.Validate())
+	}
+	if modules.Outbox {
+		r.merge(c.Outbox.Validate())
+	}
+	if modules.Ws {
+		r.merge(c.Ws.Validate())
+		if c.Ws.Cluster && !modules.Redis {
+			r.add("ws.cluster", "requires the redis module")
+		}
+		if c.Ws.Offline.Store == "redis" && !modules.Redis {
+			r.add("ws.offline.store", "redis store requires the redis module")
+		}
+		if c.Ws.Offline.Store == "gorm" && !modules.Db {
+			r.add("ws.offline.store", "gorm store requires the db module")
+		}
+		// 各签名方式都需要密钥，jwt 方式可以使用 jwt.secret
+		if c.Ws.Auth.Secret == "" && len(c.Ws.Auth.TenantSecrets) == 0 {
+			if c.Ws.Auth.Type != "jwt" {
+				r.add("ws.auth.secret", "is required unless tenant_secrets is set")
+			} else if c.Jwt.Secret == "" {
+				r.add("ws.auth.secret", "is required for jwt auth when jwt.secret is empty")
+			}
+		}
+	}
+	return r.err()
+}
+
+// 按名称排序，使报告中的问题顺序稳定
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate 校验应用配置
+func (a App) Validate() error {
+	r := new(report)
+	r.required("app.app_name", a.AppName)
+	r.oneOf("app.env", a.Env, "debug", "release", "test")
+	if a.Port != "" {
+		r.port("app.port", a.Port)
+	}
+	r.nonNegative("app.shutdown_timeout", int64(a.ShutdownTimeout))
+	return r.err()
+}
+
+// Validate 校验日志配置
+func (l Log) Validate() error {
+	r := new(report)
+	r.oneOf("log.level", l.Level, "debug", "info", "warn", "error", "dpanic", "panic", "fatal")
+	r.required("log.root_dir", l.RootDir)
+	r.nonNegative("log.max_size", int64(l.MaxSize))
+	r.nonNegative("log.max_backups", int64(l.MaxBackups))
+	r.nonNegative("log.max_age", int64(l.MaxAge))
+	return r.err()
+}
+
+// Validate 校验端口布局配置
+func (p Ports) Validate() error {
+	r := new(report)
+	r.oneOf("ports.mode", p.Mode, "", PortsModeMux, PortsModeSeparate)
+	if p.GrpcPort != "" {
+		r.port("ports.grpc_port", p.GrpcPort)
+	}
+	if p.WsPort != "" {
+		r.port("ports.ws_port", p.WsPort)
+	}
+	return r.err()
+}
+
+// Validate 校验数据库配置
+func (d Database) Validate() error {
+	return d.validate("database")
+}
+
+func (d Database) validate(path string) error {
+	r := new(report)
+	r.oneOf(path+".driver", d.Driver, "", DriverMysql, DriverPostgres, DriverSqlite)
+	switch {
+	case d.Dsn != "":
+	case d.Driver == DriverSqlite:
+		r.required(path+".database", d.Database)
+	default:
+		r.required(path+".host", d.Host)
+		r.port(path+".port", strconv.Itoa(d.Port))
+		r.required(path+".database", d.Database)
+		r.required(path+".username", d.UserName)
+	}
+	for i, replica := range d.Replicas {
+		if replica.Dsn == "" {
+			r.required(fmt.Sprintf("%s.replicas[%d].host", path, i), replica.Host)
+		}
+		if replica.Port != 0 {
+			r.port(fmt.Sprintf("%s.replicas[%d].port", path, i), strconv.Itoa(replica.Port))
+		}
+	}
+	r.nonNegative(path+".max_idle_conns", int64(d.MaxIdleConns))
+	r.nonNegative(path+".max_open_conns", int64(d.MaxOpenConns))
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		r.add(path+".max_idle_conns", "must not exceed max_open_conns (%d), got %d", d.MaxOpenConns, d.MaxIdleConns)
+	}
+	r.oneOf(path+".log_mode", d.LogMode, "", "silent", "error", "warn", "info")
+	r.nonNegative(path+".conn_max_lifetime", int64(d.ConnMaxLifetime))
+	r.nonNegative(path+".conn_max_idle_time", int64(d.ConnMaxIdleTime))
+	if d.EnableFileLogWriter {
+		r.required(path+".log_filename", d.LogFilename)
+	}
+	return r.err()
+}
+
+// Validate 校验Redis配置
+func (c Redis) Validate() error {
+	return c.validate("redis")
+}
+
+func (c Redis) validate(path string) error {
+	r := new(report)
+	r.oneOf(path+".mode", c.Mode, "", RedisStandalone, RedisSentinel, RedisCluster)
+	switch c.Mode {
+	case RedisSentinel:
+		r.required(path+".master_name", c.MasterName)
+		if len(c.Addrs) == 0 {
+			r.add(path+".addrs", "requires at least one sentinel address")
+		}
+	case RedisCluster:
+		if len(c.Addrs) == 0 {
+			r.add(path+".addrs", "requires at least one node address")
+		}
+		if c.DB != 0 {
+			r.add(path+".db", "must be 0 in cluster mode, got %d", c.DB)
+		}
+	default:
+		if len(c.Addrs) == 0 {
+			r.required(path+".host", c.Host)
+			r.port(path+".port", c.Port)
+		}
+	}
+	for i, addr := range c.Addrs {
+		if _, port, err := net.SplitHostPort(addr); err != nil {
+			r.add(fmt.Sprintf("%s.addrs[%d]", path, i), "must be host:port, got %q", addr)
+		} else {
+			r.port(fmt.Sprintf("%s.addrs[%d]", path, i), port)
+		}
+	}
+	r.nonNegative(path+".db", int64(c.DB))
+	r.nonNegative(path+".pool_size", int64(c.PoolSize))
+	r.nonNegative(path+".min_idle_conns", int64(c.MinIdleConns))
+	r.nonNegative(path+".dial_timeout", int64(c.DialTimeout))
+	r.nonNegative(path+".read_timeout", int64(c.ReadTimeout))
+	r.nonNegative(path+".write_timeout", int64(c.WriteTimeout))
+	r.nonNegative(path+".pool_timeout", int64(c.PoolTimeout))
+	if c.Tls.CertFile != "" && c.Tls.KeyFile == "" || c.Tls.CertFile == "" && c.Tls.KeyFile != "" {
+		r.add(path+".tls", "cert_file and key_file must be set together")
+	}
+	return r.err()
+}
+
+// Validate 校验Nacos配置
+func (n Nacos) Validate() error {
+	r := new(report)
+	if len(n.Servers) == 0 {
+		r.add("nacos.servers", "requires at least one server")
+	}
+	for i, s := range n.Servers {
+		r.required(fmt.Sprintf("nacos.servers[%d].server-addr", i), s.ServerAddr)
+		if s.Port < 1 || s.Port > 65535 {
+			r.add(fmt.Sprintf("nacos.servers[%d].port", i), "must be a port number between 1 and 65535, got %d", s.Port)
+		}
+	}
+	if n.Discovery.Weight < 0 {
+		r.add("nacos.discovery.weight", "must not be negative, got %v", n.Discovery.Weight)
+	}
+	if n.Discovery.Ip != "" && net.ParseIP(n.Discovery.Ip) == nil {
+		r.add("nacos.discovery.ip", "must be an IP address, got %q", n.Discovery.Ip)
+	}
+	return r.err()
+}
+
+// Validate 校验OSS配置
+func (o Oss) Validate() error {
+	r := new(report)
+	r.required("oss.endpoint", o.Endpoint)
+	r.required("oss.access_key_id", o.AccessKeyID)
+	r.required("oss.access_key_secret", o.AccessKeySecret.Value())
+	r.required("oss.bucket_name", o.BucketName)
+	return r.err()
+}
+
+// Validate 校验Xxl配置
+func (x Xxl) Validate() error {
+	r := new(report)
+	r.required("xxl.server_addr", x.ServerAddr)
+	if x.ServerAddr != "" && !strings.HasPrefix(x.ServerAddr, "http://") && !strings.HasPrefix(x.ServerAddr, "https://") {
+		r.add("xxl.server_addr", "must start with http:// or https://, got %q", x.ServerAddr)
+	}
+	return r.err()
+}
+
+// Validate 校验消息配置与配置文件中声明的消费者
+func (m Rokcetmq) Validate() error {
+	r := new(report)
+	r.oneOf("rokcetmq.broker", m.Broker, "", BrokerRocketmq, BrokerMemory)
+	if m.Broker != BrokerMemory {
+		r.required("rokcetmq.addr", m.Addr)
+	}
+	r.nonNegative("rokcetmq.max_retries", int64(m.MaxRetries))
+	r.nonNegative("rokcetmq.concurrency", int64(m.Concurrency))
+	for i, c := range m.Consumers {
+		path := fmt.Sprintf("rokcetmq.consumers[%d]", i)
+		r.required(path+".topic", c.Topic)
+		r.required(path+".handler", c.HandlerName)
+		if c.Tag != "" && c.Sql != "" {
+			r.add(path, "tag and sql are mutually exclusive")
+		}
+		r.oneOf(path+".from_where", c.FromWhere, "", ConsumeFromLast, ConsumeFromFirst, ConsumeFromTimestamp)
+		if c.FromWhere == ConsumeFromTimestamp {
+			if _, err := time.Parse("20060102150405", c.Timestamp); err != nil {
+				r.add(path+".timestamp", "must be formatted as yyyyMMddHHmmss, got %q", c.Timestamp)
+			}
+		}
+		r.nonNegative(path+".concurrency", int64(c.Concurrency))
+	}
+	return r.err()
+}
+
+// Validate 校验发件箱配置
+func (o Outbox) Validate() error {
+	r := new(report)
+	r.nonNegative("outbox.interval", int64(o.Interval))
+	r.nonNegative("outbox.batch", int64(o.Batch))
+	r.nonNegative("outbox.max_attempts", int64(o.MaxAttempts))
+	r.nonNegative("outbox.backoff", int64(o.Backoff))
+	r.nonNegative("outbox.retention", int64(o.Retention))
+	return r.err()
+}
+
+// Validate 校验WebSocket配置
+func (w Ws) Validate() error {
+	r := new(report)
+	r.nonNegative("ws.presence_ttl", w.PresenceTtl)
+	r.oneOf("ws.offline.store", w.Offline.Store, "", "memory", "redis", "gorm")
+	r.nonNegative("ws.offline.cap", int64(w.Offline.Cap))
+	r.nonNegative("ws.offline.ttl", w.Offline.Ttl)
+	r.nonNegative("ws.ack.max_retries", int64(w.Ack.MaxRetries))
+	r.nonNegative("ws.ack.backoff", w.Ack.Backoff)
+	r.oneOf("ws.auth.type", w.Auth.Type, "", "md5", "hmac", "jwt")
+	r.oneOf("ws.auth.nonce", w.Auth.Nonce, "", "memory", "redis", "none")
+	r.nonNegative("ws.auth.skew", w.Auth.Skew)
+	r.nonNegative("ws.auth.grace", w.Auth.Grace)
+	for k := range w.Auth.TenantSecrets {
+		if _, err := strconv.ParseInt(k, 10, 64); err != nil {
+			r.add("ws.auth.tenant_secrets", "tenant id must be an integer, got %q", k)
+		}
+	}
+	return r.err()
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// 所有模块都能通过校验的最小配置
+func validConfig() *Configuration {
+	return &Configuration{
+		App:      App{AppName: "hera", Env: "debug", Port: "8080"},
+		Log:      Log{Level: "info", RootDir: "logs"},
+		Database: Database{Driver: DriverSqlite, Database: "hera.db"},
+		Redis:    Redis{Host: "127.0.0.1", Port: "6379"},
+		Nacos:    Nacos{Servers: []Server{{ServerAddr: "127.0.0.1", Port: 8848}}},
+		Oss:      Oss{Endpoint: "oss", AccessKeyID: "id", AccessKeySecret: "secret", BucketName: "bucket"},
+		Xxl:      Xxl{ServerAddr: "http://xxl"},
+		Rokcetmq: Rokcetmq{Addr: "127.0.0.1:9876"},
+		Ws:       Ws{Auth: WsAuth{Secret: "secret"}},
+	}
+}
+
+func allModules() *AllModules {
+	return &AllModules{
+		Modules: Modules{Db: true, Redis: true, Nacos: true, Oss: true},
+		Xxl:     true, Rocketmq: true, Outbox: true, Ws: true, Http: true,
+	}
+}
+
+// 问题的键路径，排序后比较
+func problemPaths(err error) []string {
+	var v *ValidationError
+	if !errors.As(err, &v) {
+		return nil
+	}
+	paths := make([]string, 0, len(v.Problems))
+	for _, p := range v.Problems {
+		paths = append(paths, p.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestValidate(t *testing.T) {
+	jitter := 1.5
+	tests := []struct {
+		name    string
+		modules func(m *AllModules)
+		config  func(c *Configuration)
+		want    []string
+	}{
+		{name: "valid"},
+		{name: "app", config: func(c *Configuration) { c.App.AppName = ""; c.App.Env = "prod" }, want: []string{"app.app_name", "app.env"}},
+		{name: "log", config: func(c *Configuration) { c.Log.Level = "trace"; c.Log.RootDir = "" }, want: []string{"log.level", "log.root_dir"}},
+
+		// 端口范围
+		{name: "app port zero", config: func(c *Configuration) { c.App.Port = "0" }, want: []string{"app.port"}},
+		{name: "app port too large", config: func(c *Configuration) { c.App.Port = "65536" }, want: []string{"app.port"}},
+		{name: "app port not a number", config: func(c *Configuration) { c.App.Port = "http" }, want: []string{"app.port"}},
+		{name: "app port max", config: func(c *Configuration) { c.App.Port = "65535" }},
+		{name: "separate ports", config: func(c *Configuration) {
+			c.Ports = Ports{Mode: "both", GrpcPort: "70000", WsPort: "-1"}
+		}, want: []string{"ports.grpc_port", "ports.mode", "ports.ws_port"}},
+		{name: "app port required for http", config: func(c *Configuration) { c.App.Port = "" }, want: []string{"app.port"}},
+		{name: "app port not required for grpc with its own port", modules: func(m *AllModules) {
+			*m = AllModules{Grpc: true}
+		}, config: func(c *Configuration) { c.App.Port = ""; c.Ports.GrpcPort = "9090" }},
+		{name: "redis port", config: func(c *Configuration) { c.Redis.Port = "99999" }, want: []string{"redis.port"}},
+		{name: "nacos port", config: func(c *Configuration) { c.Nacos.Servers[0].Port = 0 }, want: []string{"nacos.servers[0].port"}},
+		{name: "database port", config: func(c *Configuration) {
+			c.Database = Database{Driver: DriverMysql, Host: "db", Port: 70000, Database: "hera", UserName: "root"}
+		}, want: []string{"database.port"}},
+
+		// 按模块校验，未启用的模块不校验
+		{name: "db disabled", modules: func(m *AllModules) { m.Db = false; m.Outbox = false }, config: func(c *Configuration) { c.Database = Database{Driver: "oracle"} }},
+		{name: "db enabled", config: func(c *Configuration) { c.Database = Database{Driver: "oracle"} }, want: []string{"database.database", "database.driver", "database.host", "database.port", "database.username"}},
+		{name: "named database", config: func(c *Configuration) {
+			c.Databases = map[string]Database{"report": {Driver: DriverSqlite}}
+		}, want: []string{"databases.report.database"}},
+		{name: "redis disabled", modules: func(m *AllModules) { m.Redis = false }, config: func(c *Configuration) { c.Redis = Redis{}; c.Lock.Redlock = []string{"a"} }},
+		{name: "redis enabled", config: func(c *Configuration) { c.Redis = Redis{} }, want: []string{"redis.host", "redis.port"}},
+		{name: "redis cluster", config: func(c *Configuration) {
+			c.Redis = Redis{Mode: RedisCluster, Addrs: []string{"a:7000", "b"}, DB: 1}
+		}, want: []string{"redis.addrs[1]", "redis.db"}},
+		{name: "redis sentinel", config: func(c *Configuration) { c.Redis = Redis{Mode: RedisSentinel} }, want: []string{"redis.addrs", "redis.master_name"}},
+		{name: "cache jitter", config: func(c *Configuration) { c.Cache.Jitter = &jitter }, want: []string{"cache.jitter"}},
+		{name: "lock redlock unknown instance", config: func(c *Configuration) {
+			c.RedisInstances = map[string]Redis{"a": {Host: "a", Port: "6379"}}
+			c.Lock.Redlock = []string{"A", "b"}
+		}, want: []string{"lock.redlock[1]"}},
+		{name: "nacos disabled", modules: func(m *AllModules) { m.Nacos = false }, config: func(c *Configuration) { c.Nacos = Nacos{} }},
+		{name: "nacos enabled", config: func(c *Configuration) { c.Nacos = Nacos{} }, want: []string{"nacos.servers"}},
+		{name: "oss enabled", config: func(c *Configuration) { c.Oss = Oss{} }, want: []string{"oss.access_key_id", "oss.access_key_secret", "oss.bucket_name", "oss.endpoint"}},
+		{name: "xxl enabled", config: func(c *Configuration) { c.Xxl.ServerAddr = "xxl" }, want: []string{"xxl.server_addr"}},
+		{name: "rocketmq memory broker", config: func(c *Configuration) { c.Rokcetmq = Rokcetmq{Broker: BrokerMemory} }},
+		{name: "rocketmq enabled", config: func(c *Configuration) {
+			c.Rokcetmq = Rokcetmq{Broker: "kafka", Consumers: []Consumer{{Topic: "order", Tag: "a", Sql: "b"}}}
+		}, want: []string{"rokcetmq.addr", "rokcetmq.broker", "rokcetmq.consumers[0]", "rokcetmq.consumers[0].handler"}},
+		{name: "outbox enabled", config: func(c *Configuration) { c.Outbox.Batch = -1 }, want: []string{"outbox.batch"}},
+
+		// WebSocket 依赖其他模块
+		{name: "ws cluster without redis", modules: func(m *AllModules) { m.Redis = false }, config: func(c *Configuration) { c.Ws.Cluster = true }, want: []string{"ws.cluster"}},
+		{name: "ws cluster with redis", config: func(c *Configuration) { c.Ws.Cluster = true }},
+		{name: "ws redis offline store without redis", modules: func(m *AllModules) { m.Redis = false }, config: func(c *Configuration) { c.Ws.Offline.Store = "redis" }, want: []string{"ws.offline.store"}},
+		{name: "ws gorm offline store without db", modules: func(m *AllModules) { m.Db = false; m.Outbox = false }, config: func(c *Configuration) { c.Ws.Offline.Store = "gorm" }, want: []string{"ws.offline.store"}},
+		{name: "ws disabled", modules: func(m *AllModules) { m.Ws = false; m.Redis = false }, config: func(c *Configuration) { c.Ws = Ws{Cluster: true} }},
+		{name: "ws md5 without secret", config: func(c *Configuration) { c.Ws.Auth = WsAuth{Type: "md5"} }, want: []string{"ws.auth.secret"}},
+		{name: "ws hmac with tenant secrets", config: func(c *Configuration) {
+			c.Ws.Auth = WsAuth{Type: "hmac", TenantSecrets: map[string]Secret{"1": "s", "x": "s"}}
+		}, want: []string{"ws.auth.tenant_secrets"}},
+		{name: "ws jwt with jwt secret", config: func(c *Configuration) { c.Ws.Auth = WsAuth{Type: "jwt"}; c.Jwt.Secret = "s" }},
+		{name: "ws jwt without secret", config: func(c *Configuration) { c.Ws.Auth = WsAuth{Type: "jwt"} }, want: []string{"ws.auth.secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			if tt.config != nil {
+				tt.config(c)
+			}
+			modules := allModules()
+			if tt.modules != nil {
+				tt.modules(modules)
+			}
+			err := c.Validate(modules)
+			got := problemPaths(err)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Validate() problems = %v, want %v\n%v", got, tt.want, err)
+			}
+		})
+	}
+}
+
+// 未传入模块时只校验公共配置
+func TestValidateNilModules(t *testing.T) {
+	c := validConfig()
+	c.Redis = Redis{}
+	c.App.Port = ""
+	if err := c.Validate(nil); err != nil {
+		t.Fatalf("Validate(nil) = %v", err)
+	}
+}
+
+// 所有问题一次性报告
+func TestValidateReportsAll(t *testing.T) {
+	c := validConfig()
+	c.App.AppName = ""
+	c.Redis = Redis{}
+	c.Ws.Auth.Secret = ""
+	err := c.Validate(allModules())
+	var v *ValidationError
+	if !errors.As(err, &v) || len(v.Problems) != 4 {
+		t.Fatalf("Validate() = %v, want 4 problems", err)
+	}
+}
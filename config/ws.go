@@ -0,0 +1,35 @@
+package config
+
+type Ws struct {
+	Cluster     bool      `mapstructure:"cluster" json:"cluster" yaml:"cluster"`                // 是否开启集群模式，通过 Redis 在多个节点间投递消息
+	Prefix      string    `mapstructure:"prefix" json:"prefix" yaml:"prefix"`                   // Redis 键与频道前缀，默认 hera:ws:{app_name}
+	PresenceTtl int64     `mapstructure:"presence_ttl" json:"presence_ttl" yaml:"presence_ttl"` // 在线记录有效期（秒），由心跳刷新，默认 180 秒
+	Offline     WsOffline `mapstructure:"offline" json:"offline" yaml:"offline"`
+	Ack         WsAck     `mapstructure:"ack" json:"ack" yaml:"ack"`
+	Auth        WsAuth    `mapstructure:"auth" json:"auth" yaml:"auth"`
+	ReportTopic string    `mapstructure:"report_topic" json:"report_topic" yaml:"report_topic"` // 服务端发送的指令的状态报告MQ主题，为空时仅记录日志
+}
+
+// WsAck 指令确认与重试配置，开启后带 requestId 的指令需要接收方回复 STATUS_ACK
+type WsAck struct {
+	Enable     bool  `mapstructure:"enable" json:"enable" yaml:"enable"`
+	MaxRetries int   `mapstructure:"max_retries" json:"max_retries" yaml:"max_retries"` // 最大重发次数，默认 3
+	Backoff    int64 `mapstructure:"backoff" json:"backoff" yaml:"backoff"`             // 首次重发前的等待时间（毫秒），之后每次翻倍，默认 2000
+}
+
+// WsOffline 离线消息配置，仅保存 offlineSend 为 true 的指令
+type WsOffline struct {
+	Store string `mapstructure:"store" json:"store" yaml:"store"` // memory、redis 或 gorm，为空时不保存离线消息
+	Cap   int    `mapstructure:"cap" json:"cap" yaml:"cap"`       // 单用户最多保存的消息数量，默认 100
+	Ttl   int64  `mapstructure:"ttl" json:"ttl" yaml:"ttl"`       // 消息有效期（秒），默认 7 天
+}
+
+// WsAuth 客户端认证配置，客户端需在宽限期内发送通过认证的心跳包
+type WsAuth struct {
+	Type          string            `mapstructure:"type" json:"type" yaml:"type"`                               // md5（默认，兼容旧版客户端）、hmac 或 jwt
+	Secret        Secret            `mapstructure:"secret" json:"secret" yaml:"secret"`                         // 默认密钥，jwt 方式为空时使用 Jwt.Secret
+	TenantSecrets map[string]Secret `mapstructure:"tenant_secrets" json:"tenant_secrets" yaml:"tenant_secrets"` // 按租户ID配置的密钥
+	Skew          int64             `mapstructure:"skew" json:"skew" yaml:"skew"`                               // 允许的时钟偏差（秒），默认 300
+	Grace         int64             `mapstructure:"grace" json:"grace" yaml:"grace"`                            // 未认证连接的宽限期（秒），默认 10
+	Nonce         string            `mapstructure:"nonce" json:"nonce" yaml:"nonce"`                            // 重放保护：memory、redis 或 none，默认集群模式使用 redis，否则使用 memory
+}
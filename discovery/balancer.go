@@ -0,0 +1,60 @@
+package discovery
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(Weighted, &weightedPickerBuilder{}, base.Config{HealthCheck: true}))
+}
+
+// 地址属性中实例权重的键
+type weightKey struct{}
+
+type weightedPickerBuilder struct{}
+
+func (*weightedPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &weightedPicker{}
+	for sc, sci := range info.ReadySCs {
+		weight := 1.0
+		if w, ok := sci.Address.BalancerAttributes.Value(weightKey{}).(float64); ok && w > 0 {
+			weight = w
+		}
+		p.conns = append(p.conns, &weightedConn{sc: sc, weight: weight})
+		p.total += weight
+	}
+	return p
+}
+
+type weightedConn struct {
+	sc      balancer.SubConn
+	weight  float64
+	current float64
+}
+
+// 平滑加权轮询，权重高的实例被更频繁地选中，且选择结果分布均匀
+type weightedPicker struct {
+	mu    sync.Mutex
+	conns []*weightedConn
+	total float64
+}
+
+func (p *weightedPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var best *weightedConn
+	for _, c := range p.conns {
+		c.current += c.weight
+		if best == nil || c.current > best.current {
+			best = c
+		}
+	}
+	best.current -= p.total
+	return balancer.PickResult{SubConn: best.sc}, nil
+}
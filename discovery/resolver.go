@@ -0,0 +1,150 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+// Scheme 服务发现地址的协议，格式为 nacos:///{service}?group=&cluster=&lb=
+const Scheme = "nacos"
+
+// 负载均衡策略
+const (
+	RoundRobin = "round_robin"    // 轮询，默认
+	Weighted   = "nacos_weighted" // 按实例权重分配
+)
+
+// 注册时写入实例元数据的端口键
+const (
+	MetaHttpPort = "http_port"
+	MetaGrpcPort = "grpc_port"
+	MetaWsPort   = "ws_port"
+)
+
+// ClientFunc 获取 Nacos 服务发现客户端，在首次解析地址时调用
+type ClientFunc func() (naming_client.INamingClient, error)
+
+// Register 注册 nacos 协议的 gRPC 地址解析器
+func Register(client ClientFunc) {
+	resolver.Register(NewBuilder(client))
+}
+
+// NewBuilder 创建 nacos 协议的 gRPC 地址解析器
+func NewBuilder(client ClientFunc) resolver.Builder {
+	return &builder{client: client}
+}
+
+type builder struct {
+	client ClientFunc
+}
+
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	service := strings.TrimPrefix(target.Endpoint(), "/")
+	if service == "" {
+		return nil, errors.New("nacos resolver: service name is required, e.g. nacos:///service-name")
+	}
+	client, err := b.client()
+	if err != nil {
+		return nil, fmt.Errorf("nacos resolver: %w", err)
+	}
+	query := target.URL.Query()
+	lb := query.Get("lb")
+	switch lb {
+	case "", "round_robin":
+		lb = RoundRobin
+	case "weighted", Weighted:
+		lb = Weighted
+	default:
+		return nil, fmt.Errorf("nacos resolver: unknown lb %q", lb)
+	}
+	r := &nacosResolver{
+		cc:            cc,
+		client:        client,
+		serviceConfig: cc.ParseServiceConfig(fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]}`, lb)),
+	}
+	var clusters []string
+	if cluster := query.Get("cluster"); cluster != "" {
+		clusters = strings.Split(cluster, ",")
+	}
+	r.param = &vo.SubscribeParam{
+		ServiceName:       service,
+		GroupName:         query.Get("group"),
+		Clusters:          clusters,
+		SubscribeCallback: r.update,
+	}
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	// 实例变更时由 Nacos 推送
+	if err := client.Subscribe(r.param); err != nil {
+		return nil, fmt.Errorf("nacos resolver: subscribe %s: %w", service, err)
+	}
+	return r, nil
+}
+
+type nacosResolver struct {
+	cc            resolver.ClientConn
+	client        naming_client.INamingClient
+	param         *vo.SubscribeParam
+	serviceConfig *serviceconfig.ParseResult
+	mu            sync.Mutex
+}
+
+// ResolveNow 主动查询一次健康的实例
+func (r *nacosResolver) ResolveNow(resolver.ResolveNowOptions) {
+	instances, err := r.client.SelectInstances(vo.SelectInstancesParam{
+		ServiceName: r.param.ServiceName,
+		GroupName:   r.param.GroupName,
+		Clusters:    r.param.Clusters,
+		HealthyOnly: true,
+	})
+	r.update(instances, err)
+}
+
+func (r *nacosResolver) Close() {
+	_ = r.client.Unsubscribe(r.param)
+}
+
+// 用健康且启用的实例更新连接地址，gRPC 端口优先取实例元数据中的 grpc_port
+func (r *nacosResolver) update(instances []model.Instance, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.cc.ReportError(fmt.Errorf("nacos resolver: %s: %w", r.param.ServiceName, err))
+		return
+	}
+	addrs := make([]resolver.Address, 0, len(instances))
+	for _, ins := range instances {
+		if !ins.Enable || !ins.Healthy || ins.Weight <= 0 {
+			continue
+		}
+		port := strconv.FormatUint(ins.Port, 10)
+		if p := ins.Metadata[MetaGrpcPort]; p != "" {
+			port = p
+		}
+		addrs = append(addrs, resolver.Address{
+			Addr:               net.JoinHostPort(ins.Ip, port),
+			BalancerAttributes: attributes.New(weightKey{}, ins.Weight),
+		})
+	}
+	if len(addrs) == 0 {
+		r.cc.ReportError(fmt.Errorf("nacos resolver: no healthy instance of %s", r.param.ServiceName))
+		return
+	}
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs, ServiceConfig: r.serviceConfig}); err != nil {
+		r.cc.ReportError(err)
+	}
+}
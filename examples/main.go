@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/robfig/cron/v3"
 	"github.com/succko/hera"
+	"github.com/succko/hera/config"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"net/http"
@@ -12,7 +13,7 @@ import (
 func main() {
 	defer hera.DeferHandle()
 	// 注册模块
-	modules := &hera.Modules{
+	modules := &config.Modules{
 		Db:        true,
 		Redis:     true,
 		Nacos:     true,
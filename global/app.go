@@ -1,13 +1,20 @@
 package global
 
 import (
+	"context"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/apache/rocketmq-client-go/v2"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
+	"github.com/succko/hera/broker"
+	"github.com/succko/hera/cache"
 	"github.com/succko/hera/config"
+	"github.com/succko/hera/lifecycle"
+	"github.com/succko/hera/migrate"
 	"github.com/xxl-job/xxl-job-executor-go"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -15,28 +22,40 @@ import (
 )
 
 type app struct {
-	ConfigViper       *viper.Viper
-	Config            config.Configuration
-	Log               *zap.Logger
-	DB                *gorm.DB
-	Redis             *redis.Client
-	Xxl               xxl.Executor
-	Oss               *oss.Bucket
-	RocketMqProducer  rocketmq.Producer
-	RocketMqConsumers []rocketmq.PushConsumer
-	RunConfig         RunConfig
-	Modules           *config.AllModules
+	ConfigViper                 *viper.Viper
+	Log                         *zap.Logger
+	DB                          *gorm.DB
+	DBs                         map[string]*gorm.DB
+	Redis                       redis.UniversalClient
+	RedisInstances              map[string]redis.UniversalClient
+	Cache                       *cache.Cache
+	Xxl                         xxl.Executor
+	Oss                         *oss.Bucket
+	RocketMqProducer            rocketmq.Producer
+	RocketMqTransactionProducer rocketmq.TransactionProducer
+	RocketMqConsumers           []rocketmq.PushConsumer
+	Broker                      broker.Broker
+	Naming                      naming_client.INamingClient
+	RunConfig                   RunConfig
+	Modules                     *config.AllModules
+	Lifecycle                   *lifecycle.Manager
 }
 
 type RunConfig struct {
 	Nacos             map[string]any
 	Cron              func(c *cron.Cron)
 	RocketMqConsumers map[string]func(message []byte)
+	RocketMqHandlers  map[string]func(ctx context.Context, msg *primitive.MessageExt) error
+	RocketMqSpecs     []config.Consumer
+	RocketMqNamed     map[string]func(ctx context.Context, msg *primitive.MessageExt) error
+	RocketMqChecker   func(msg *primitive.MessageExt) primitive.LocalTransactionState
 	MetaData          []func()
 	Grpc              func(server *grpc.Server)
 	Xxl               func(exec xxl.Executor)
 	Router            func(router *gin.Engine)
 	Swagger           func()
+	Modules           []lifecycle.Module
+	Migrations        []migrate.Migration
 }
 
 var App = new(app)
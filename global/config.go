@@ -0,0 +1,64 @@
+package global
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/succko/hera/config"
+	"go.uber.org/zap"
+)
+
+// ConfigWatcher 配置变更回调，old 与 new 均为只读快照，changes 为字段级差异
+type ConfigWatcher func(old, new *config.Configuration, changes []config.Change)
+
+var (
+	current  atomic.Pointer[config.Configuration]
+	watchers []ConfigWatcher
+	// 串行化配置替换与回调注册
+	configMu sync.Mutex
+)
+
+// 配置加载之前返回的空配置
+var empty config.Configuration
+
+// Config 当前生效配置的只读快照，热更新时整体原子替换，不要修改返回的配置
+func Config() *config.Configuration {
+	if c := current.Load(); c != nil {
+		return c
+	}
+	return &empty
+}
+
+// WatchConfig 注册配置变更回调
+func WatchConfig(w ConfigWatcher) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	watchers = append(watchers, w)
+}
+
+// SwapConfig 原子替换当前配置，并通知有变更的回调，返回字段级差异
+func SwapConfig(next *config.Configuration) []config.Change {
+	configMu.Lock()
+	defer configMu.Unlock()
+	old := current.Swap(next)
+	if old == nil {
+		return nil
+	}
+	changes := config.Diff(old, next)
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, w := range watchers {
+		notify(w, old, next, changes)
+	}
+	return changes
+}
+
+func notify(w ConfigWatcher, old, next *config.Configuration, changes []config.Change) {
+	defer func() {
+		if r := recover(); r != nil {
+			zap.L().Error("config watcher panic", zap.Any("panic", r))
+		}
+	}()
+	w(old, next, changes)
+}
@@ -0,0 +1,16 @@
+package global
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DB 按名称获取配置文件 databases 中声明的数据库连接，名称不区分大小写，为空或 default 时返回 App.DB
+func DB(name string) *gorm.DB {
+	name = strings.ToLower(name)
+	if name == "" || name == "default" {
+		return App.DB
+	}
+	return App.DBs[name]
+}
@@ -2,11 +2,36 @@ package global
 
 import (
 	"context"
-	"github.com/go-redis/redis/v8"
-	"github.com/succko/hera/utils"
+	"errors"
 	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/succko/hera/lock"
+	"go.uber.org/zap"
 )
 
+// Locker 按配置创建分布式锁，默认使用 App.Redis，配置 lock.redlock 时在对应的具名实例上使用 Redlock 算法
+func Locker() *lock.Client {
+	c := Config().Lock
+	nodes := []redis.UniversalClient{App.Redis}
+	if len(c.Redlock) > 0 {
+		nodes = make([]redis.UniversalClient, 0, len(c.Redlock))
+		for _, name := range c.Redlock {
+			if client := Redis(name); client != nil {
+				nodes = append(nodes, client)
+			}
+		}
+	}
+	return lock.New(lock.Options{
+		Prefix:        c.Prefix,
+		TTL:           time.Duration(c.Ttl) * time.Millisecond,
+		RetryInterval: time.Duration(c.RetryInterval) * time.Millisecond,
+	}, nodes...)
+}
+
+// Interface 简单的分布式锁
+//
+// Deprecated: 使用 Locker，支持上下文、自动续期、重入与防护令牌，并返回错误
 type Interface interface {
 	Get() bool
 	Block(seconds int64) bool
@@ -14,60 +39,63 @@ type Interface interface {
 	ForceRelease()
 }
 
-type lock struct {
-	context context.Context
-	name    string // 锁名称
-	owner   string // 锁标识
-	seconds int64  // 有效期
+type simpleLock struct {
+	client *lock.Client
+	name   string
+	held   *lock.Lock
 }
 
-// 释放锁 Lua 脚本，防止任何客户端都能解锁
-const releaseLockLuaScript = `
-if redis.call("get",KEYS[1]) == ARGV[1] then
-    return redis.call("del",KEYS[1])
-else
-    return 0
-end
-`
-
-// 生成锁
+// Lock 生成锁，seconds 为有效期，持有期间不自动续期
+//
+// Deprecated: 使用 Locker
 func Lock(name string, seconds int64) Interface {
-	return &lock{
-		context.Background(),
-		name,
-		utils.RandString(16),
-		seconds,
+	return &simpleLock{
+		// 与旧版使用相同的键，滚动升级期间新旧副本仍然互斥
+		client: lock.New(lock.Options{
+			TTL:        time.Duration(seconds) * time.Second,
+			NoWatchdog: true,
+			RawKey:     true,
+		}, App.Redis),
+		name: name,
 	}
 }
 
 // 获取锁
-func (l *lock) Get() bool {
-	return App.Redis.SetNX(l.context, l.name, l.owner, time.Duration(l.seconds)*time.Second).Val()
+func (l *simpleLock) Get() bool {
+	held, err := l.client.Obtain(context.Background(), l.name)
+	if err != nil {
+		if !errors.Is(err, lock.ErrNotObtained) {
+			zap.L().Error("lock obtain failed", zap.String("name", l.name), zap.Error(err))
+		}
+		return false
+	}
+	l.held = held
+	return true
 }
 
 // 阻塞一段时间，尝试获取锁
-func (l *lock) Block(seconds int64) bool {
-	starting := time.Now().Unix()
-	for {
-		if !l.Get() {
-			time.Sleep(time.Duration(1) * time.Second)
-			if time.Now().Unix()-seconds >= starting {
-				return false
-			}
-		} else {
-			return true
-		}
+func (l *simpleLock) Block(seconds int64) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second)
+	defer cancel()
+	held, err := l.client.Acquire(ctx, l.name)
+	if err != nil {
+		return false
 	}
+	l.held = held
+	return true
 }
 
 // 释放锁
-func (l *lock) Release() bool {
-	luaScript := redis.NewScript(releaseLockLuaScript)
-	result := luaScript.Run(l.context, App.Redis, []string{l.name}, l.owner).Val().(int64)
-	return result != 0
+func (l *simpleLock) Release() bool {
+	if l.held == nil {
+		return false
+	}
+	err := l.held.Release(context.Background())
+	l.held = nil
+	return err == nil
 }
 
 // 强制释放锁
-func (l *lock) ForceRelease() {
-	App.Redis.Del(l.context, l.name).Val()
+func (l *simpleLock) ForceRelease() {
+	_ = l.client.ForceRelease(context.Background(), l.name)
 }
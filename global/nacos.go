@@ -0,0 +1,61 @@
+package global
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/succko/hera/config"
+	"go.uber.org/zap"
+)
+
+// NacosConfig 业务在 Nacos 中的配置，通过 RegisterNacos 注册。
+// 配置变更时解析为新值后整体原子替换，读取方通过 Get 获取只读快照。
+type NacosConfig[T any] struct {
+	current  atomic.Pointer[T]
+	mu       sync.Mutex
+	watchers []func(old, new *T, changes []config.Change)
+}
+
+// Get 当前配置的只读快照，尚未加载时返回零值，不要修改返回的值
+func (c *NacosConfig[T]) Get() *T {
+	if v := c.current.Load(); v != nil {
+		return v
+	}
+	return new(T)
+}
+
+// Watch 注册配置变更回调，changes 为字段级差异
+func (c *NacosConfig[T]) Watch(w func(old, new *T, changes []config.Change)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watchers = append(c.watchers, w)
+}
+
+// Load 将内容解析为新值并原子替换，解析失败时保留原值
+func (c *NacosConfig[T]) Load(content []byte, unmarshal func([]byte, any) error) error {
+	fresh := new(T)
+	if err := unmarshal(content, fresh); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	old := c.current.Swap(fresh)
+	if old == nil {
+		return nil
+	}
+	changes := config.DiffValues(old, fresh)
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, w := range c.watchers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					zap.L().Error("nacos config watcher panic", zap.Any("panic", r))
+				}
+			}()
+			w(old, fresh, changes)
+		}()
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package global
+
+import (
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis 按名称获取配置文件 redis_instances 中声明的Redis实例，名称不区分大小写，为空或 default 时返回 App.Redis
+func Redis(name string) redis.UniversalClient {
+	name = strings.ToLower(name)
+	if name == "" || name == "default" {
+		return App.Redis
+	}
+	return App.RedisInstances[name]
+}
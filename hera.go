@@ -1,22 +1,27 @@
 package hera
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/robfig/cron/v3"
 	"github.com/succko/hera/bootstrap"
 	"github.com/succko/hera/config"
 	"github.com/succko/hera/global"
-	"github.com/succko/hera/metadata"
+	"github.com/succko/hera/lifecycle"
+	"github.com/succko/hera/migrate"
+	"github.com/succko/hera/mq"
 	"github.com/xxl-job/xxl-job-executor-go"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"net"
-	"sync"
+	"os"
+	"time"
 )
 
 var _modules = new(config.AllModules)
 
-// RegisterNacos 注册nacos配置
+// RegisterNacos 注册nacos配置，键为 dataId，值为 *global.NacosConfig[T] 时支持热更新
 func RegisterNacos(f func() map[string]any) {
 	_modules.Nacos = true
 	global.App.RunConfig.Nacos = f()
@@ -34,6 +39,39 @@ func RegisterRocketMqConsumers(f func() map[string]func(message []byte)) {
 	global.App.RunConfig.RocketMqConsumers = f()
 }
 
+// RegisterRocketMqHandlers 注册rocketmq消费者，处理失败时重试，超过最大重试次数后投递到死信主题
+func RegisterRocketMqHandlers(f func() map[string]mq.Handler) {
+	_modules.Rocketmq = true
+	global.App.RunConfig.RocketMqHandlers = f()
+}
+
+// RegisterRocketMqSpecs 以声明的方式注册rocketmq消费者，可指定标签、消费组、广播、顺序消费等
+func RegisterRocketMqSpecs(specs ...config.Consumer) {
+	_modules.Rocketmq = true
+	global.App.RunConfig.RocketMqSpecs = append(global.App.RunConfig.RocketMqSpecs, specs...)
+}
+
+// RegisterRocketMqHandler 注册具名的消费处理函数，供配置文件中声明的消费者引用
+func RegisterRocketMqHandler(name string, h mq.Handler) {
+	_modules.Rocketmq = true
+	if global.App.RunConfig.RocketMqNamed == nil {
+		global.App.RunConfig.RocketMqNamed = make(map[string]mq.Handler)
+	}
+	global.App.RunConfig.RocketMqNamed[name] = h
+}
+
+// RegisterRocketMqTransactionChecker 注册事务消息回查函数，注册后可使用 mq.Producer.SendTransaction 发送事务消息
+func RegisterRocketMqTransactionChecker(c mq.Checker) {
+	_modules.Rocketmq = true
+	global.App.RunConfig.RocketMqChecker = c
+}
+
+// RegisterOutbox 启用发件箱，通过 outbox.Add 在数据库事务中记录的消息由后台发布到rocketmq，需同时启用Db模块
+func RegisterOutbox() {
+	_modules.Rocketmq = true
+	_modules.Outbox = true
+}
+
 // RegisterMetaData 注册元数据
 func RegisterMetaData(f func() []func()) {
 	_modules.Metadata = true
@@ -60,6 +98,17 @@ func RegisterSwagger(f func()) {
 	global.App.RunConfig.Swagger = f
 }
 
+// RegisterMigrations 注册数据库迁移，配置 migrate.auto 后启动时自动执行，也可通过命令行 migrate status|up|down|to 执行，
+// SQL 文件形式的迁移可通过 migrate.FromFS 读取
+func RegisterMigrations(migrations ...migrate.Migration) {
+	global.App.RunConfig.Migrations = append(global.App.RunConfig.Migrations, migrations...)
+}
+
+// RegisterModule 注册自定义模块，模块按声明的依赖顺序启动，并按相反顺序停止
+func RegisterModule(modules ...lifecycle.Module) {
+	global.App.RunConfig.Modules = append(global.App.RunConfig.Modules, modules...)
+}
+
 func RegisterModules(modules *config.Modules) {
 	_modules.Db = modules.Db
 	_modules.Redis = modules.Redis
@@ -71,35 +120,61 @@ func RegisterModules(modules *config.Modules) {
 
 // RunHttpServer 启动http服务
 func RunHttpServer() {
+	_modules.Http = true
 	err := run()
-	global.App.Modules.Http = true
 	if err != nil {
-		zap.L().Fatal("run http server error", zap.Error(err))
+		fatal("run http server error", err)
 	}
 	// 创建 TCP 监听器
-	l, _ := net.Listen("tcp", ":"+global.App.Config.App.Port)
+	l, err := net.Listen("tcp", ":"+global.Config().App.Port)
+	if err != nil {
+		fatal("listen error", err)
+	}
 	bootstrap.RunHttpServer(l)
+	if err := bootstrap.RegisterInstance(); err != nil {
+		fatal("register nacos instance error", err)
+	}
+	wait()
 }
 
 // RunGrpcServer 启动grpc服务
 func RunGrpcServer() {
+	_modules.Grpc = true
 	err := run()
-	global.App.Modules.Grpc = true
 	if err != nil {
-		zap.L().Fatal("run http server error", zap.Error(err))
+		fatal("run grpc server error", err)
+	}
+	// 创建 TCP 监听器
+	l, err := net.Listen("tcp", ":"+bootstrap.GrpcPort())
+	if err != nil {
+		fatal("listen error", err)
 	}
-	bootstrap.RunGrpcServer()
+	bootstrap.RunGrpcServer(l)
+	if err := bootstrap.RegisterInstance(); err != nil {
+		fatal("register nacos instance error", err)
+	}
+	wait()
 }
 
 func RunWsServer() {
+	_modules.Ws = true
 	err := run()
-	global.App.Modules.Ws = true
 	if err != nil {
-		zap.L().Fatal("run http server error", zap.Error(err))
+		fatal("run ws server error", err)
 	}
 	// 创建 TCP 监听器
-	l, _ := net.Listen("tcp", ":"+global.App.Config.App.Port)
+	l, err := net.Listen("tcp", ":"+global.Config().App.Port)
+	if err != nil {
+		fatal("listen error", err)
+	}
+	if err := bootstrap.RunHub(); err != nil {
+		fatal("run ws hub error", err)
+	}
 	bootstrap.RunWsServer(l)
+	if err := bootstrap.RegisterInstance(); err != nil {
+		fatal("register nacos instance error", err)
+	}
+	wait()
 }
 
 // RunCMux 启动cmux服务
@@ -108,15 +183,34 @@ func RunCMux(runHttpServer bool, runGrpcServer bool, runWsServer bool) {
 		zap.L().Fatal("RunCMux server error")
 		return
 	}
+	_modules.Http = runHttpServer
+	_modules.Grpc = runGrpcServer
+	_modules.Ws = runWsServer
 	err := run()
-	global.App.Modules.Http = runHttpServer
-	global.App.Modules.Grpc = runGrpcServer
-	global.App.Modules.Ws = runWsServer
 	if err != nil {
-		zap.L().Fatal("run http server error", zap.Error(err))
+		fatal("run cmux server error", err)
 	}
-	// 创建 TCP 监听器
-	bootstrap.RunCMux()
+	// 按配置的端口布局启动各协议服务
+	if err := bootstrap.RunCMux(); err != nil {
+		fatal("run cmux server error", err)
+	}
+	if err := bootstrap.RegisterInstance(); err != nil {
+		fatal("register nacos instance error", err)
+	}
+	wait()
+}
+
+// 阻塞直到收到退出信号，并依次停止接收连接、排空请求、停止模块
+func wait() {
+	if err := bootstrap.Shutdown.Wait(); err != nil {
+		zap.L().Error("shutdown error", zap.Error(err))
+	}
+}
+
+// 释放已初始化的资源后退出，zap Fatal 会跳过调用方的 defer
+func fatal(msg string, err error) {
+	DeferHandle()
+	zap.L().Fatal(msg, zap.Error(err))
 }
 
 func run() error {
@@ -133,6 +227,11 @@ func run() error {
 		}
 	}
 
+	// 通过 nacos:///{service} 发现其他服务
+	if _modules.Nacos {
+		bootstrap.InitializeDiscovery()
+	}
+
 	// 初始化flag
 	if _modules.Flag {
 		bootstrap.InitializeFlag()
@@ -141,130 +240,66 @@ func run() error {
 	// 初始化日志
 	global.App.Log = bootstrap.InitializeLog()
 
-	// 初始化数据库
-	if _modules.Db {
-		global.App.DB = bootstrap.InitializeDB()
-	}
-
-	var wg sync.WaitGroup
-
-	inits := make([]func() error, 0)
-	if _modules.Db {
-		inits = append(inits, // 初始化验证器
-			func() error {
-				defer wg.Done()
-				return bootstrap.InitializeValidator()
-			})
-	}
-
-	if _modules.Redis {
-		inits = append(inits, // 初始化Redis
-			func() error {
-				defer wg.Done()
-				global.App.Redis = bootstrap.InitializeRedis()
-				return nil
-			})
-	}
-
-	if _modules.Xxl {
-		inits = append(inits, // 初始化Xxl
-			func() error {
-				defer wg.Done()
-				global.App.Xxl = bootstrap.InitializeXxl()
-				return nil
-			})
-	}
-
-	if _modules.Metadata {
-		inits = append(inits, // 初始化元数据
-			func() error {
-				defer wg.Done()
-				metadata.Loader.InitializeMetadata()
-				return nil
-			})
-	}
-
-	if _modules.Oss {
-		inits = append(inits, // 初始化OSS
-			func() error {
-				defer wg.Done()
-				global.App.Oss = bootstrap.InitializeOss()
-				return nil
-			})
+	// 校验配置，配置有误时拒绝启动
+	if err := bootstrap.ValidateConfig(); err != nil {
+		// 日志写入文件，同时输出到标准错误便于排查
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return err
 	}
 
-	if _modules.Cron {
-		inits = append(inits, // 初始化Cron
-			func() error {
-				defer wg.Done()
-				bootstrap.InitializeCron()
-				return nil
-			})
+	// 命令行 migrate 子命令执行后退出
+	if handled, err := bootstrap.RunMigrateCommand(os.Stdout); handled {
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
 
-	if _modules.Rocketmq {
-		inits = append(inits, // 初始化RocketMq
-			func() error {
-				defer wg.Done()
-				var w sync.WaitGroup
-				w.Add(2)
-				go func() {
-					defer w.Done()
-					global.App.RocketMqProducer = bootstrap.InitializeRocketMqProducer()
-				}()
-				go func() {
-					defer w.Done()
-					global.App.RocketMqConsumers = bootstrap.InitializeRocketMqConsumers()
-				}()
-				w.Wait()
-				return nil
-			})
+	// 按依赖顺序启动各模块
+	manager := lifecycle.New()
+	builtins := []struct {
+		on     bool
+		module func() lifecycle.Module
+	}{
+		{_modules.Db, bootstrap.DbModule},
+		{_modules.Validator, bootstrap.ValidatorModule},
+		{_modules.Redis, bootstrap.RedisModule},
+		{_modules.Xxl, bootstrap.XxlModule},
+		{_modules.Metadata, bootstrap.MetadataModule},
+		{_modules.Oss, bootstrap.OssModule},
+		{_modules.Cron, bootstrap.CronModule},
+		{_modules.Rocketmq, bootstrap.RocketmqModule},
+		{_modules.Outbox, bootstrap.OutboxModule},
 	}
-
-	wg.Add(len(inits))
-
-	for _, f := range inits {
-		go func(initFunc func() error) {
-			if err := initFunc(); err != nil {
-				zap.L().Error("Initialization task failed", zap.Error(err))
+	for _, b := range builtins {
+		if b.on {
+			if err := manager.Register(b.module()); err != nil {
+				return err
 			}
-		}(f)
+		}
 	}
+	if err := manager.Register(global.App.RunConfig.Modules...); err != nil {
+		return err
+	}
+	global.App.Lifecycle = manager
+	// 服务排空后再停止模块，例如先停止消费者、定时任务，最后关闭数据库与Redis
+	bootstrap.Shutdown.Register(bootstrap.StageModule, "modules", manager.Stop)
 
-	// 等待所有初始化任务完成
-	wg.Wait()
-
-	return nil
+	return manager.Start(context.Background())
 }
 
 func DeferHandle() {
 	zap.L().Info("defer handle trigger")
 
-	// 程序关闭前，释放数据库连接
-	if global.App.DB != nil {
-		db, _ := global.App.DB.DB()
-		if err := db.Close(); err == nil {
-			zap.L().Info("defer db close success")
+	// 程序关闭前，按启动的相反顺序停止各模块
+	if global.App.Lifecycle != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := global.App.Lifecycle.Stop(ctx); err == nil {
+			zap.L().Info("defer modules stop success")
 		} else {
-			zap.L().Error("defer db close error", zap.Error(err))
-		}
-	}
-
-	if global.App.RocketMqProducer != nil {
-		if err := global.App.RocketMqProducer.Shutdown(); err == nil {
-			zap.L().Info("defer mq producer shutdown success")
-		} else {
-			zap.L().Error("defer mq producer shutdown error", zap.Error(err))
-		}
-	}
-
-	if global.App.RocketMqConsumers != nil && len(global.App.RocketMqConsumers) > 0 {
-		for _, consumer := range global.App.RocketMqConsumers {
-			if err := consumer.Shutdown(); err == nil {
-				zap.L().Info("defer mq consumer shutdown success")
-			} else {
-				zap.L().Error("defer mq consumer shutdown error", zap.Error(err))
-			}
+			zap.L().Error("defer modules stop error", zap.Error(err))
 		}
 	}
 
@@ -0,0 +1,213 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Manager 按依赖关系管理模块的启动与停止。
+//
+// 启动时按拓扑顺序分层执行，同一层内的模块互不依赖，并行启动；任意模块启动失败时，
+// 不再启动后续层级，已启动的模块按相反顺序停止，并返回聚合后的错误。
+type Manager struct {
+	run     sync.Mutex // 串行执行启动与停止，模块回调期间不持有 mu
+	mu      sync.Mutex
+	modules map[string]Module
+	names   []string // 注册顺序
+	started []Module // 启动成功的模块，按启动顺序
+}
+
+// New 创建 Manager 实例。
+func New() *Manager {
+	return &Manager{
+		modules: make(map[string]Module),
+	}
+}
+
+// Register 注册模块，模块名称重复时返回错误。
+func (m *Manager) Register(modules ...Module) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, module := range modules {
+		name := module.Name()
+		if name == "" {
+			return errors.New("lifecycle: module name is empty")
+		}
+		if _, ok := m.modules[name]; ok {
+			return fmt.Errorf("lifecycle: module %q registered twice", name)
+		}
+		m.modules[name] = module
+		m.names = append(m.names, name)
+	}
+	return nil
+}
+
+// Module 按名称获取已注册的模块。
+func (m *Manager) Module(name string) (Module, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	module, ok := m.modules[name]
+	return module, ok
+}
+
+// Levels 计算启动层级，每一层只依赖之前层级中的模块。
+func (m *Manager) Levels() ([][]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.levels()
+}
+
+func (m *Manager) levels() ([][]string, error) {
+	indegree := make(map[string]int, len(m.names))
+	dependents := make(map[string][]string, len(m.names))
+	for _, name := range m.names {
+		indegree[name] += 0
+		for _, dep := range m.modules[name].Dependencies() {
+			if _, ok := m.modules[dep]; !ok {
+				return nil, fmt.Errorf("lifecycle: module %q depends on unregistered module %q", name, dep)
+			}
+			indegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var levels [][]string
+	var current []string
+	for _, name := range m.names {
+		if indegree[name] == 0 {
+			current = append(current, name)
+		}
+	}
+	visited := 0
+	for len(current) > 0 {
+		levels = append(levels, current)
+		visited += len(current)
+		var next []string
+		for _, name := range current {
+			for _, dependent := range dependents[name] {
+				indegree[dependent]--
+				if indegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		current = next
+	}
+
+	if visited != len(m.names) {
+		var cycle []string
+		for name, degree := range indegree {
+			if degree > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("lifecycle: dependency cycle between modules %s", strings.Join(cycle, ", "))
+	}
+	return levels, nil
+}
+
+// Start 按拓扑顺序启动所有模块。
+func (m *Manager) Start(ctx context.Context) error {
+	m.run.Lock()
+	defer m.run.Unlock()
+
+	// 在锁内取得层级与模块的快照，启动模块时不持有锁，模块可在启动过程中调用 Health 等方法
+	m.mu.Lock()
+	levels, err := m.levels()
+	modules := make(map[string]Module, len(m.modules))
+	for name, module := range m.modules {
+		modules[name] = module
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, level := range levels {
+		var (
+			wg   sync.WaitGroup
+			emu  sync.Mutex
+			errs []error
+			ok   = make([]bool, len(level))
+		)
+		wg.Add(len(level))
+		for i, name := range level {
+			go func(i int, module Module) {
+				defer wg.Done()
+				if err := module.Start(ctx); err != nil {
+					emu.Lock()
+					errs = append(errs, fmt.Errorf("start module %q: %w", module.Name(), err))
+					emu.Unlock()
+					return
+				}
+				ok[i] = true
+				zap.L().Info("lifecycle module started", zap.String("module", module.Name()))
+			}(i, modules[name])
+		}
+		wg.Wait()
+
+		// 保持层内注册顺序，使停止顺序可预期
+		m.mu.Lock()
+		for i, name := range level {
+			if ok[i] {
+				m.started = append(m.started, modules[name])
+			}
+		}
+		m.mu.Unlock()
+
+		if len(errs) > 0 {
+			if err := m.stop(ctx); err != nil {
+				errs = append(errs, err)
+			}
+			return errors.Join(errs...)
+		}
+	}
+	return nil
+}
+
+// Stop 按启动的相反顺序停止所有已启动的模块，返回聚合后的错误。
+func (m *Manager) Stop(ctx context.Context) error {
+	m.run.Lock()
+	defer m.run.Unlock()
+	return m.stop(ctx)
+}
+
+// 调用方需持有 run
+func (m *Manager) stop(ctx context.Context) error {
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		module := started[i]
+		if err := module.Stop(ctx); err != nil {
+			zap.L().Error("lifecycle module stop error", zap.String("module", module.Name()), zap.Error(err))
+			errs = append(errs, fmt.Errorf("stop module %q: %w", module.Name(), err))
+			continue
+		}
+		zap.L().Info("lifecycle module stopped", zap.String("module", module.Name()))
+	}
+	return errors.Join(errs...)
+}
+
+// Health 检查所有已启动模块的健康状态，返回模块名称到错误的映射，健康的模块值为 nil。
+func (m *Manager) Health(ctx context.Context) map[string]error {
+	m.mu.Lock()
+	started := append([]Module(nil), m.started...)
+	m.mu.Unlock()
+
+	result := make(map[string]error, len(started))
+	for _, module := range started {
+		result[module.Name()] = module.Health(ctx)
+	}
+	return result
+}
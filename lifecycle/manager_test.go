@@ -0,0 +1,201 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录模块启动与停止顺序
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *recorder) module(name string, deps ...string) *Hooks {
+	return &Hooks{
+		ModuleName: name,
+		DependsOn:  deps,
+		OnStart: func(ctx context.Context) error {
+			r.add("start " + name)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func TestLevels(t *testing.T) {
+	r := &recorder{}
+	m := New()
+	if err := m.Register(r.module("app", "db", "redis"), r.module("db"), r.module("redis"), r.module("outbox", "db", "app")); err != nil {
+		t.Fatal(err)
+	}
+	levels, err := m.Levels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"db", "redis"}, {"app"}, {"outbox"}}
+	if !reflect.DeepEqual(levels, want) {
+		t.Fatalf("Levels() = %v, want %v", levels, want)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	m := New()
+	if err := m.Register(&Hooks{ModuleName: "db"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Register(&Hooks{ModuleName: "db"}); err == nil {
+		t.Fatal("Register() duplicate module, want error")
+	}
+	if err := m.Register(&Hooks{}); err == nil {
+		t.Fatal("Register() empty name, want error")
+	}
+}
+
+func TestLevelsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules []Module
+		want    string
+	}{
+		{"unregistered", []Module{&Hooks{ModuleName: "a", DependsOn: []string{"b"}}}, `depends on unregistered module "b"`},
+		{"cycle", []Module{
+			&Hooks{ModuleName: "a", DependsOn: []string{"c"}},
+			&Hooks{ModuleName: "b", DependsOn: []string{"a"}},
+			&Hooks{ModuleName: "c", DependsOn: []string{"b"}},
+			&Hooks{ModuleName: "d"},
+		}, "dependency cycle between modules a, b, c"},
+		{"self", []Module{&Hooks{ModuleName: "a", DependsOn: []string{"a"}}}, "dependency cycle between modules a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			if err := m.Register(tt.modules...); err != nil {
+				t.Fatal(err)
+			}
+			_, err := m.Levels()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Levels() error = %v, want %q", err, tt.want)
+			}
+			if err := m.Start(context.Background()); err == nil {
+				t.Fatal("Start() want error")
+			}
+		})
+	}
+}
+
+func TestStartStopOrder(t *testing.T) {
+	r := &recorder{}
+	m := New()
+	if err := m.Register(r.module("app", "cache"), r.module("cache", "db"), r.module("db")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start db", "start cache", "start app", "stop app", "stop cache", "stop db"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+	// 已停止的模块不会重复停止
+	if err := m.Stop(context.Background()); err != nil || len(r.events) != len(want) {
+		t.Fatalf("second Stop() = %v, events %v", err, r.events)
+	}
+}
+
+// 启动失败时不再启动后续层级，已启动的模块按相反顺序停止
+func TestStartFailure(t *testing.T) {
+	r := &recorder{}
+	boom := errors.New("boom")
+	cache := r.module("cache", "db")
+	cache.OnStart = func(ctx context.Context) error {
+		r.add("start cache")
+		return boom
+	}
+	m := New()
+	if err := m.Register(r.module("db"), r.module("redis"), cache, r.module("app", "cache")); err != nil {
+		t.Fatal(err)
+	}
+	err := m.Start(context.Background())
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), `start module "cache"`) {
+		t.Fatalf("Start() error = %v", err)
+	}
+	want := []string{"start db", "start redis", "start cache", "stop redis", "stop db"}
+	if len(r.events) != len(want) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+	// 同一层内并行启动，顺序不定
+	if got := r.events[:2]; !(reflect.DeepEqual(got, want[:2]) || reflect.DeepEqual(got, []string{"start redis", "start db"})) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+	if !reflect.DeepEqual(r.events[2:], want[2:]) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+}
+
+func TestStopErrorsAggregated(t *testing.T) {
+	r := &recorder{}
+	db := r.module("db")
+	db.OnStop = func(ctx context.Context) error { return errors.New("db stop") }
+	app := r.module("app", "db")
+	app.OnStop = func(ctx context.Context) error { return errors.New("app stop") }
+	m := New()
+	if err := m.Register(db, app); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	err := m.Stop(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "db stop") || !strings.Contains(err.Error(), "app stop") {
+		t.Fatalf("Stop() error = %v", err)
+	}
+}
+
+// 模块启动过程中调用 Health 不会死锁
+func TestHealthDuringStart(t *testing.T) {
+	m := New()
+	unhealthy := errors.New("unhealthy")
+	var during map[string]error
+	db := &Hooks{ModuleName: "db", OnHealth: func(ctx context.Context) error { return unhealthy }}
+	app := &Hooks{ModuleName: "app", DependsOn: []string{"db"}, OnStart: func(ctx context.Context) error {
+		during = m.Health(ctx)
+		return nil
+	}}
+	if err := m.Register(db, app); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- m.Start(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start() deadlocked")
+	}
+	if len(during) != 1 || during["db"] != unhealthy {
+		t.Fatalf("Health() during start = %v", during)
+	}
+	if health := m.Health(context.Background()); len(health) != 2 || health["app"] != nil {
+		t.Fatalf("Health() = %v", health)
+	}
+}
@@ -0,0 +1,68 @@
+package lifecycle
+
+import "context"
+
+// 内置模块名称，用户模块可通过这些名称声明依赖。
+const (
+	ModuleDb        = "db"
+	ModuleValidator = "validator"
+	ModuleRedis     = "redis"
+	ModuleXxl       = "xxl"
+	ModuleMetadata  = "metadata"
+	ModuleOss       = "oss"
+	ModuleCron      = "cron"
+	ModuleRocketmq  = "rocketmq"
+	ModuleOutbox    = "outbox"
+)
+
+// Module 描述一个受生命周期管理的模块。
+type Module interface {
+	// Name 模块名称，在同一个 Manager 中必须唯一。
+	Name() string
+	// Dependencies 依赖的模块名称，依赖模块会先于本模块启动、后于本模块停止。
+	Dependencies() []string
+	// Start 启动模块。
+	Start(ctx context.Context) error
+	// Stop 停止模块并释放资源。
+	Stop(ctx context.Context) error
+	// Health 检查模块健康状态，返回 nil 表示健康。
+	Health(ctx context.Context) error
+}
+
+// Hooks 以函数的形式快速定义一个 Module，未设置的函数视为空操作。
+type Hooks struct {
+	ModuleName string
+	DependsOn  []string
+	OnStart    func(ctx context.Context) error
+	OnStop     func(ctx context.Context) error
+	OnHealth   func(ctx context.Context) error
+}
+
+func (h *Hooks) Name() string {
+	return h.ModuleName
+}
+
+func (h *Hooks) Dependencies() []string {
+	return h.DependsOn
+}
+
+func (h *Hooks) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+func (h *Hooks) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+func (h *Hooks) Health(ctx context.Context) error {
+	if h.OnHealth == nil {
+		return nil
+	}
+	return h.OnHealth(ctx)
+}
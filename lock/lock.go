@@ -0,0 +1,368 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrNotObtained 锁被其他持有者占用，或 Redlock 模式下未获得多数节点
+	ErrNotObtained = errors.New("lock: not obtained")
+	// ErrNotHeld 锁已释放或已过期
+	ErrNotHeld = errors.New("lock: not held")
+)
+
+// DefaultPrefix 锁键的默认前缀
+const DefaultPrefix = "hera:lock:"
+
+// 获取锁并递增防护令牌，防护令牌在同一个锁名称上单调递增；未传入令牌键时返回 1
+var obtainScript = redis.NewScript(`
+if redis.call("set", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+    if #KEYS > 1 then
+        return redis.call("incr", KEYS[2])
+    end
+    return 1
+end
+return 0
+`)
+
+// 仅持有者可以续期
+var renewScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+    return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// 仅持有者可以释放
+var releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+    return redis.call("del", KEYS[1])
+end
+return 0
+`)
+
+// Options 锁选项
+type Options struct {
+	Prefix        string        // 锁键前缀，默认 hera:lock:
+	TTL           time.Duration // 锁的有效期，默认 30s，持有期间由看门狗每 TTL/3 续期一次
+	RetryInterval time.Duration // Acquire 的重试间隔，默认 100ms，实际等待时间附加随机抖动
+	NoWatchdog    bool          // 不自动续期，锁在 TTL 后过期
+	RawKey        bool          // 锁键直接使用名称，不加前缀且没有防护令牌，兼容旧版 global.Lock 的键
+}
+
+// Client 基于 Redis 的分布式锁。
+// 只有一个节点时为普通的单节点锁；多个相互独立的节点时使用 Redlock 算法，需要在多数节点上加锁成功。
+type Client struct {
+	nodes  []redis.UniversalClient
+	quorum int
+	opts   Options
+}
+
+// New 创建分布式锁，传入多个独立的 Redis 节点时使用 Redlock 算法，建议为奇数个
+func New(opts Options, nodes ...redis.UniversalClient) *Client {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 30 * time.Second
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 100 * time.Millisecond
+	}
+	return &Client{nodes: nodes, quorum: len(nodes)/2 + 1, opts: opts}
+}
+
+// Lock 一次持有的锁，并发安全
+type Lock struct {
+	client *Client
+	name   string
+	key    string
+	owner  string
+	token  int64
+
+	mu       sync.Mutex
+	count    int  // 重入次数
+	released bool // 已释放或已丢失
+	stop     chan struct{}
+	lost     chan struct{}
+}
+
+// 上下文中已持有的锁，用于重入
+type contextKey struct{ name string }
+
+// NewContext 返回携带该锁的上下文，使用该上下文再次获取同名锁时重入而不是等待
+func NewContext(ctx context.Context, l *Lock) context.Context {
+	return context.WithValue(ctx, contextKey{l.name}, l)
+}
+
+func fromContext(ctx context.Context, c *Client, name string) *Lock {
+	l, _ := ctx.Value(contextKey{name}).(*Lock)
+	if l == nil || l.client != c {
+		return nil
+	}
+	return l
+}
+
+// Obtain 尝试获取一次锁，被占用时返回 ErrNotObtained，Redis 不可用时返回对应错误。
+// ctx 中已持有同名锁（见 NewContext）时重入，返回同一个锁并增加重入次数。
+func (c *Client) Obtain(ctx context.Context, name string) (*Lock, error) {
+	if l := fromContext(ctx, c, name); l != nil {
+		return l, l.reenter()
+	}
+	if len(c.nodes) == 0 {
+		return nil, errors.New("lock: no redis node")
+	}
+	owner, err := newOwner()
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{
+		client: c,
+		name:   name,
+		key:    c.key(name),
+		owner:  owner,
+		count:  1,
+		stop:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	token, err := l.obtain(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l.token = token
+	if !c.opts.NoWatchdog {
+		go l.watchdog()
+	}
+	return l, nil
+}
+
+// Acquire 获取锁，被占用时按重试间隔等待，直到获取成功或 ctx 结束。
+// ctx 中携带的同名锁已丢失时立即返回 ErrNotHeld
+func (c *Client) Acquire(ctx context.Context, name string) (*Lock, error) {
+	for {
+		l, err := c.Obtain(ctx, name)
+		if err == nil {
+			return l, nil
+		}
+		if errors.Is(err, ErrNotHeld) {
+			return nil, err
+		}
+		wait := c.opts.RetryInterval + time.Duration(mrand.Int63n(int64(c.opts.RetryInterval)/2+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("lock %s: %w", name, errors.Join(ctx.Err(), err))
+		case <-timer.C:
+		}
+	}
+}
+
+// 锁键，哈希标签使锁键与令牌键位于同一个集群槽位
+func (c *Client) key(name string) string {
+	if c.opts.RawKey {
+		return name
+	}
+	return c.opts.Prefix + "{" + name + "}"
+}
+
+// ForceRelease 不校验持有者，直接删除所有节点上的锁
+func (c *Client) ForceRelease(ctx context.Context, name string) error {
+	key := c.key(name)
+	errs := make([]error, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		errs = append(errs, node.Del(ctx, key).Err())
+	}
+	return errors.Join(errs...)
+}
+
+// 在各节点上加锁，成功的节点达到多数且剩余有效期为正时成功，否则撤销已加的锁。
+// 防护令牌取加锁成功节点中的最大值。
+func (l *Lock) obtain(ctx context.Context) (int64, error) {
+	c := l.client
+	keys := []string{l.key}
+	if !c.opts.RawKey {
+		keys = append(keys, l.key+":fence")
+	}
+	start := time.Now()
+	var (
+		token int64
+		acked int
+		errs  []error
+	)
+	for _, node := range c.nodes {
+		n, err := obtainScript.Run(ctx, node, keys, l.owner, c.opts.TTL.Milliseconds()).Int64()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if n > 0 {
+			acked++
+			if n > token && !c.opts.RawKey {
+				token = n
+			}
+		}
+	}
+	if acked >= c.quorum && time.Since(start) < c.opts.TTL-drift(c.opts.TTL) {
+		return token, nil
+	}
+	if acked > 0 {
+		_ = l.releaseAll(context.Background())
+	}
+	if len(errs) > 0 {
+		return 0, fmt.Errorf("lock %s: %w", l.name, errors.Join(errs...))
+	}
+	return 0, ErrNotObtained
+}
+
+// 时钟漂移的余量
+func drift(ttl time.Duration) time.Duration {
+	return ttl/100 + 2*time.Millisecond
+}
+
+func (l *Lock) reenter() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return ErrNotHeld
+	}
+	l.count++
+	return nil
+}
+
+// Name 锁名称
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Token 防护令牌，RawKey 模式下为 0。对锁保护的资源写入时一并提交，资源方拒绝小于已见令牌的写入，避免锁过期后旧持有者的写入生效
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Lost 锁续期失败而丢失时关闭，持有者应停止操作受保护的资源
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Refresh 立即续期一次，锁已丢失时返回 ErrNotHeld，Redis 不可用时返回对应错误
+func (l *Lock) Refresh(ctx context.Context) error {
+	c := l.client
+	start := time.Now()
+	var (
+		acked int
+		errs  []error
+	)
+	for _, node := range c.nodes {
+		n, err := renewScript.Run(ctx, node, []string{l.key}, l.owner, c.opts.TTL.Milliseconds()).Int64()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if n > 0 {
+			acked++
+		}
+	}
+	if acked >= c.quorum && time.Since(start) < c.opts.TTL-drift(c.opts.TTL) {
+		return nil
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("lock %s: %w", l.name, errors.Join(errs...))
+	}
+	return ErrNotHeld
+}
+
+// Release 释放锁，重入时只减少重入次数，最后一次释放时删除锁
+func (l *Lock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return ErrNotHeld
+	}
+	if l.count--; l.count > 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	l.released = true
+	close(l.stop)
+	l.mu.Unlock()
+	return l.releaseAll(ctx)
+}
+
+func (l *Lock) releaseAll(ctx context.Context) error {
+	var (
+		acked int
+		errs  []error
+	)
+	for _, node := range l.client.nodes {
+		n, err := releaseScript.Run(ctx, node, []string{l.key}, l.owner).Int64()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if n > 0 {
+			acked++
+		}
+	}
+	// Redlock 模式下多数节点释放成功即可，其余节点上的锁会自然过期
+	if acked >= l.client.quorum {
+		return nil
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("lock %s: %w", l.name, errors.Join(errs...))
+	}
+	if acked == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// 持有期间每 TTL/3 续期一次，续期失败且剩余有效期不足时认为锁已丢失
+func (l *Lock) watchdog() {
+	ttl := l.client.opts.TTL
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	deadline := time.Now().Add(ttl)
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+		err := l.Refresh(ctx)
+		cancel()
+		if err == nil {
+			deadline = time.Now().Add(ttl)
+			continue
+		}
+		// 锁已被删除或过期，或 Redis 持续不可用直到有效期将尽
+		if err == ErrNotHeld || time.Until(deadline) <= ttl/3 {
+			l.mu.Lock()
+			if !l.released {
+				l.released = true
+				close(l.lost)
+			}
+			l.mu.Unlock()
+			return
+		}
+	}
+}
+
+// 持有者标识，使用加密随机数避免并发下重复
+func newOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("lock: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,296 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newNode(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1, DialTimeout: 200 * time.Millisecond})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+func TestObtainRelease(t *testing.T) {
+	ctx := context.Background()
+	mr, node := newNode(t)
+	c := New(Options{NoWatchdog: true}, node)
+
+	l, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists(DefaultPrefix + "{order}") {
+		t.Fatal("lock key not set")
+	}
+	if _, err := c.Obtain(ctx, "order"); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("Obtain() while held = %v, want ErrNotObtained", err)
+	}
+	if err := l.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(DefaultPrefix + "{order}") {
+		t.Fatal("lock key not deleted")
+	}
+	if err := l.Release(ctx); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("second Release() = %v, want ErrNotHeld", err)
+	}
+}
+
+// 锁过期后被其他持有者获取，旧持有者不能释放新持有者的锁
+func TestReleaseAfterExpiry(t *testing.T) {
+	ctx := context.Background()
+	mr, node := newNode(t)
+	c := New(Options{TTL: time.Second, NoWatchdog: true}, node)
+	old, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(2 * time.Second)
+	cur, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Release(ctx); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("Release() expired lock = %v, want ErrNotHeld", err)
+	}
+	if err := cur.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() current lock = %v", err)
+	}
+}
+
+func TestFencingToken(t *testing.T) {
+	ctx := context.Background()
+	_, node := newNode(t)
+	c := New(Options{NoWatchdog: true}, node)
+	var last int64
+	for i := 0; i < 5; i++ {
+		l, err := c.Obtain(ctx, "order")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Token() <= last {
+			t.Fatalf("token %d not greater than %d", l.Token(), last)
+		}
+		last = l.Token()
+		if err := l.Release(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 不同名称的令牌相互独立
+	l, err := c.Obtain(ctx, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Token() != 1 {
+		t.Fatalf("token of another name = %d, want 1", l.Token())
+	}
+}
+
+func TestRawKey(t *testing.T) {
+	ctx := context.Background()
+	mr, node := newNode(t)
+	c := New(Options{RawKey: true, NoWatchdog: true}, node)
+	l, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists("order") || len(mr.Keys()) != 1 {
+		t.Fatalf("keys = %v, want [order]", mr.Keys())
+	}
+	if l.Token() != 0 {
+		t.Fatalf("token = %d, want 0", l.Token())
+	}
+}
+
+func TestReentrant(t *testing.T) {
+	ctx := context.Background()
+	mr, node := newNode(t)
+	c := New(Options{NoWatchdog: true}, node)
+	l, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = NewContext(ctx, l)
+	again, err := c.Acquire(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != l {
+		t.Fatal("reentrant Acquire() returned another lock")
+	}
+	// 其他客户端的同名锁不重入
+	if _, err := New(Options{NoWatchdog: true}, node).Obtain(ctx, "order"); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("Obtain() from another client = %v, want ErrNotObtained", err)
+	}
+	if err := again.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists(DefaultPrefix + "{order}") {
+		t.Fatal("lock released before the outermost Release")
+	}
+	if err := l.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(DefaultPrefix + "{order}") {
+		t.Fatal("lock not released")
+	}
+	// 上下文中的锁已释放时立即返回
+	start := time.Now()
+	if _, err := c.Acquire(ctx, "order"); !errors.Is(err, ErrNotHeld) || time.Since(start) > time.Second {
+		t.Fatalf("Acquire() with released lock = %v after %v", err, time.Since(start))
+	}
+}
+
+func TestAcquireWaits(t *testing.T) {
+	ctx := context.Background()
+	_, node := newNode(t)
+	c := New(Options{NoWatchdog: true, RetryInterval: 10 * time.Millisecond}, node)
+	l, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(100*time.Millisecond, func() { _ = l.Release(context.Background()) })
+	next, err := c.Acquire(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Token() <= l.Token() {
+		t.Fatalf("token %d not greater than %d", next.Token(), l.Token())
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Acquire(timeout, "order"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire() timeout = %v", err)
+	}
+}
+
+// 看门狗在持有期间续期，锁被删除后通过 Lost 通知
+func TestWatchdog(t *testing.T) {
+	ctx := context.Background()
+	mr, node := newNode(t)
+	c := New(Options{TTL: 300 * time.Millisecond}, node)
+	l, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := DefaultPrefix + "{order}"
+	// miniredis 不随真实时间过期，每次检查前快进至剩余有效期，未续期时锁会过期
+	for i := 0; i < 5; i++ {
+		time.Sleep(150 * time.Millisecond)
+		ttl := mr.TTL(key)
+		if ttl <= 0 {
+			t.Fatalf("lock expired after %d checks", i)
+		}
+		mr.FastForward(ttl - 100*time.Millisecond)
+	}
+	select {
+	case <-l.Lost():
+		t.Fatal("lock lost while renewing")
+	default:
+	}
+
+	mr.Del(key)
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Lost() not closed after the key was deleted")
+	}
+	if err := l.Release(ctx); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("Release() lost lock = %v, want ErrNotHeld", err)
+	}
+	if _, err := c.Acquire(NewContext(ctx, l), "order"); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("Acquire() with lost lock = %v, want ErrNotHeld", err)
+	}
+}
+
+func TestWatchdogStopsOnRelease(t *testing.T) {
+	ctx := context.Background()
+	mr, node := newNode(t)
+	c := New(Options{TTL: 150 * time.Millisecond}, node)
+	l, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if mr.Exists(DefaultPrefix + "{order}") {
+		t.Fatal("watchdog renewed a released lock")
+	}
+	select {
+	case <-l.Lost():
+		t.Fatal("Lost() closed after Release")
+	default:
+	}
+}
+
+func TestRedlockQuorum(t *testing.T) {
+	ctx := context.Background()
+	mrs := make([]*miniredis.Miniredis, 3)
+	nodes := make([]redis.UniversalClient, 3)
+	for i := range nodes {
+		mrs[i], nodes[i] = newNode(t)
+	}
+	key := DefaultPrefix + "{order}"
+	c := New(Options{NoWatchdog: true}, nodes...)
+
+	// 少数节点被占用时仍可获得
+	_ = mrs[0].Set(key, "other")
+	l, err := c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := mrs[0].Get(key); v != "other" {
+		t.Fatal("released a lock held by another owner")
+	}
+
+	// 多数节点被占用时失败，并撤销已加的锁
+	_ = mrs[1].Set(key, "other")
+	if _, err := c.Obtain(ctx, "order"); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("Obtain() without quorum = %v, want ErrNotObtained", err)
+	}
+	if mrs[2].Exists(key) {
+		t.Fatal("partial lock not rolled back")
+	}
+	mrs[0].Del(key)
+	mrs[1].Del(key)
+
+	// 一个节点不可用时仍可获得与释放，防护令牌取多数节点中的最大值
+	_ = mrs[2].Set(key+":fence", "41")
+	mrs[0].Close()
+	l, err = c.Obtain(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Token() != 42 {
+		t.Fatalf("token = %d, want 42", l.Token())
+	}
+	if err := l.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 多数节点不可用时返回错误
+	mrs[1].Close()
+	if _, err := c.Obtain(ctx, "order"); err == nil || errors.Is(err, ErrNotObtained) {
+		t.Fatalf("Obtain() with nodes down = %v, want redis error", err)
+	}
+	if mrs[2].Exists(key) {
+		t.Fatal("partial lock not rolled back")
+	}
+}
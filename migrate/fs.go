@@ -0,0 +1,136 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SQL 迁移文件名：{version}_{name}.up.sql 与 {version}_{name}.down.sql
+var sqlFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// FromFS 从目录中读取 SQL 迁移文件，通常与 embed.FS 一起使用。
+// 文件中的多条语句以行尾的分号分隔，逐条执行。函数、存储过程与触发器等语句体内含分号时，
+// 用 -- +migrate StatementBegin 与 -- +migrate StatementEnd 包围，其间的内容作为一条语句执行。
+func FromFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		match := sqlFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names %q and %q", version, m.Name, match[2])
+		}
+		exec, err := execSql(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+		if match[3] == "up" {
+			m.Up = exec
+		} else {
+			m.Down = exec
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %d %s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func execSql(content string) (func(tx *gorm.DB) error, error) {
+	statements, err := splitSql(content)
+	if err != nil {
+		return nil, err
+	}
+	return func(tx *gorm.DB) error {
+		for _, s := range statements {
+			if err := tx.Exec(s).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// 语句块标记，块内的分号不拆分语句
+const (
+	statementBegin = "-- +migrate StatementBegin"
+	statementEnd   = "-- +migrate StatementEnd"
+)
+
+// 按行尾的分号拆分语句，忽略空行与 -- 注释行；语句块内的内容原样保留为一条语句
+func splitSql(content string) ([]string, error) {
+	var statements []string
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			statements = append(statements, s)
+		}
+		b.Reset()
+	}
+	inBlock := false
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == statementBegin:
+			if inBlock {
+				return nil, errors.New("nested " + statementBegin)
+			}
+			flush()
+			inBlock = true
+			continue
+		case trimmed == statementEnd:
+			if !inBlock {
+				return nil, errors.New(statementEnd + " without " + statementBegin)
+			}
+			flush()
+			inBlock = false
+			continue
+		case inBlock:
+			b.WriteString(line)
+			b.WriteString("\n")
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "--"):
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	if inBlock {
+		return nil, errors.New(statementBegin + " without " + statementEnd)
+	}
+	flush()
+	return statements, nil
+}
@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 在同一个数据库连接上持有咨询锁执行 fn，其他实例等待直到锁释放或超时。
+// fn 中的迁移同样使用该连接，连接池上限为 1 时也不会因等待第二个连接而死锁
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		unlock, err := m.lock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return fn(conn)
+	})
+}
+
+// 按数据库类型获取咨询锁，SQLite 的写入本身由文件锁串行化，无需加锁
+func (m *Migrator) lock(ctx context.Context, conn *gorm.DB) (func(), error) {
+	name := "hera_migrate:" + m.opts.Table
+	switch conn.Dialector.Name() {
+	case "mysql":
+		var got int
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", name, int(m.opts.LockTimeout.Seconds())).Scan(&got).Error; err != nil {
+			return nil, err
+		}
+		if got != 1 {
+			return nil, ErrLockTimeout
+		}
+		return func() { conn.Exec("SELECT RELEASE_LOCK(?)", name) }, nil
+	case "postgres":
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(name))
+		key := int64(h.Sum64())
+		deadline := time.Now().Add(m.opts.LockTimeout)
+		for {
+			var got bool
+			if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&got).Error; err != nil {
+				return nil, err
+			}
+			if got {
+				return func() { conn.Exec("SELECT pg_advisory_unlock(?)", key) }, nil
+			}
+			if time.Now().After(deadline) {
+				return nil, ErrLockTimeout
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(500 * time.Millisecond):
+			}
+		}
+	}
+	return func() {}, nil
+}
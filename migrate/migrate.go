@@ -0,0 +1,195 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DefaultTable 记录已执行迁移的表
+const DefaultTable = "schema_migrations"
+
+// Migration 一个版本的迁移，版本号递增且唯一，通常使用时间戳如 20240101120000
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // 为空时该版本不可回滚
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// 已执行的迁移记录
+type record struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+// Options 迁移选项
+type Options struct {
+	Table       string        // 记录表名，默认 schema_migrations
+	LockTimeout time.Duration // 等待其他实例完成迁移的最长时间，默认 1 分钟
+}
+
+// Migrator 按版本顺序执行迁移，每个迁移在独立的事务中执行并记录到记录表。
+// 多个实例同时启动时通过数据库咨询锁保证只有一个实例执行迁移。
+// 注意 MySQL 的 DDL 会隐式提交事务，失败的迁移可能已部分生效。
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	opts       Options
+}
+
+// New 创建迁移器，版本号重复时返回错误
+func New(db *gorm.DB, migrations []Migration, opts Options) (*Migrator, error) {
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = time.Minute
+	}
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %d has no up", m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+	}
+	return &Migrator{db: db, migrations: sorted, opts: opts}, nil
+}
+
+// 已执行的迁移，按版本号索引
+func (m *Migrator) applied(db *gorm.DB) (map[int64]record, error) {
+	if err := db.Table(m.opts.Table).AutoMigrate(&record{}); err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := db.Table(m.opts.Table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// Status 所有已注册迁移的执行状态
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		s := Status{Version: mg.Version, Name: mg.Name}
+		if r, ok := applied[mg.Version]; ok {
+			at := r.AppliedAt
+			s.Applied, s.AppliedAt = true, &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// Up 执行所有未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, -1)
+}
+
+// Down 按版本从高到低回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; !ok {
+				continue
+			}
+			if err := m.down(conn, m.migrations[i]); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// To 迁移到指定版本：执行不高于该版本的未执行迁移，回滚高于该版本的已执行迁移，version 为负数时执行全部
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	return m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && version >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; ok && mg.Version > version {
+				if err := m.down(conn, mg); err != nil {
+					return err
+				}
+			}
+		}
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok || version >= 0 && mg.Version > version {
+				continue
+			}
+			if err := m.up(conn, mg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) up(db *gorm.DB, mg Migration) error {
+	start := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := mg.Up(tx); err != nil {
+			return err
+		}
+		return tx.Table(m.opts.Table).Create(&record{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d %s up: %w", mg.Version, mg.Name, err)
+	}
+	zap.L().Info("migration applied", zap.Int64("version", mg.Version), zap.String("name", mg.Name), zap.Duration("cost", time.Since(start)))
+	return nil
+}
+
+func (m *Migrator) down(db *gorm.DB, mg Migration) error {
+	if mg.Down == nil {
+		return fmt.Errorf("migration %d %s has no down", mg.Version, mg.Name)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := mg.Down(tx); err != nil {
+			return err
+		}
+		return tx.Table(m.opts.Table).Where("version = ?", mg.Version).Delete(&record{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d %s down: %w", mg.Version, mg.Name, err)
+	}
+	zap.L().Info("migration rolled back", zap.Int64("version", mg.Version), zap.String("name", mg.Name))
+	return nil
+}
+
+// ErrLockTimeout 等待其他实例完成迁移超时
+var ErrLockTimeout = errors.New("migrate: timeout waiting for the migration lock")
@@ -0,0 +1,255 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	// 与咨询锁相同，迁移在单个连接上执行
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+// 每个迁移创建一张表，回滚时删除
+func tableMigration(version int64, table string, log *[]string) Migration {
+	return Migration{
+		Version: version,
+		Name:    table,
+		Up: func(tx *gorm.DB) error {
+			*log = append(*log, "up "+table)
+			return tx.Exec("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			*log = append(*log, "down "+table)
+			return tx.Exec("DROP TABLE " + table).Error
+		},
+	}
+}
+
+func appliedVersions(t *testing.T, m *Migrator) []int64 {
+	t.Helper()
+	status, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int64
+	for _, s := range status {
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+func TestNewRejectsInvalid(t *testing.T) {
+	up := func(tx *gorm.DB) error { return nil }
+	if _, err := New(nil, []Migration{{Version: 2, Up: up}, {Version: 1, Up: up}, {Version: 2, Up: up}}, Options{}); err == nil || !strings.Contains(err.Error(), "duplicate migration version 2") {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := New(nil, []Migration{{Version: 1}}, Options{}); err == nil {
+		t.Fatal("New() without up, want error")
+	}
+}
+
+func TestUpToDown(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	var log []string
+	// 注册顺序与版本顺序无关
+	m, err := New(db, []Migration{
+		tableMigration(3, "c", &log),
+		tableMigration(1, "a", &log),
+		tableMigration(2, "b", &log),
+	}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.To(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Fatalf("after To(2) applied = %v", got)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 已执行的迁移不会重复执行
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Fatalf("after Up applied = %v", got)
+	}
+	// 回滚高于目标版本的迁移，从高到低
+	if err := m.To(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := appliedVersions(t, m); len(got) != 0 {
+		t.Fatalf("after Down applied = %v", got)
+	}
+	want := []string{"up a", "up b", "up c", "down c", "down b", "down a"}
+	if !reflect.DeepEqual(log, want) {
+		t.Fatalf("log = %v, want %v", log, want)
+	}
+}
+
+func TestDownSteps(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	m, err := New(openDB(t), []Migration{tableMigration(1, "a", &log), tableMigration(2, "b", &log), tableMigration(3, "c", &log)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []int64{1}) {
+		t.Fatalf("applied = %v", got)
+	}
+}
+
+// 失败的迁移在事务中回滚，不记录为已执行，之后的迁移不执行
+func TestUpFailure(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	var log []string
+	boom := errors.New("boom")
+	bad := Migration{Version: 2, Name: "bad", Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE TABLE half (id INTEGER)").Error; err != nil {
+			return err
+		}
+		return boom
+	}}
+	m, err := New(db, []Migration{tableMigration(1, "a", &log), bad, tableMigration(3, "c", &log)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(ctx); !errors.Is(err, boom) {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []int64{1}) {
+		t.Fatalf("applied = %v", got)
+	}
+	if db.Migrator().HasTable("half") || db.Migrator().HasTable("c") {
+		t.Fatal("failed migration left changes")
+	}
+}
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/20240101000000_init.up.sql": {Data: []byte(`
+-- 用户表
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY,
+    name TEXT
+);
+CREATE TABLE audit (user_id INTEGER, action TEXT);
+
+-- +migrate StatementBegin
+CREATE TRIGGER users_audit AFTER INSERT ON users
+BEGIN
+    INSERT INTO audit (user_id, action) VALUES (NEW.id, 'insert');
+END;
+-- +migrate StatementEnd
+`)},
+		"migrations/20240101000000_init.down.sql": {Data: []byte("DROP TABLE audit;\nDROP TABLE users;\n")},
+		"migrations/20240102000000_seed.up.sql":   {Data: []byte("INSERT INTO users (name) VALUES ('a');")},
+		"migrations/README.md":                    {Data: []byte("ignored")},
+	}
+	ms, err := FromFS(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[0].Version != 20240101000000 || ms[0].Name != "init" || ms[1].Down != nil {
+		t.Fatalf("FromFS() = %+v", ms)
+	}
+	db := openDB(t)
+	m, err := New(db, ms, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var audits int64
+	if err := db.Table("audit").Count(&audits).Error; err != nil || audits != 1 {
+		t.Fatalf("audit rows = %d, err %v", audits, err)
+	}
+	// 20240102000000 没有 down 文件，不能回滚
+	if err := m.Down(context.Background(), 1); err == nil {
+		t.Fatal("Down() without down file, want error")
+	}
+}
+
+func TestFromFSErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"no up", fstest.MapFS{"m/1_a.down.sql": {Data: []byte("SELECT 1;")}}},
+		{"different names", fstest.MapFS{"m/1_a.up.sql": {Data: []byte("SELECT 1;")}, "m/1_b.down.sql": {Data: []byte("SELECT 1;")}}},
+		{"unclosed block", fstest.MapFS{"m/1_a.up.sql": {Data: []byte(statementBegin + "\nSELECT 1;")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromFS(tt.fsys, "m"); err == nil {
+				t.Fatal("FromFS() want error")
+			}
+		})
+	}
+}
+
+func TestSplitSql(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{"single", "SELECT 1;", []string{"SELECT 1;"}, false},
+		{"multi line", "CREATE TABLE a (\n  id INT\n);\r\nSELECT 1;\n", []string{"CREATE TABLE a (\n  id INT\n);", "SELECT 1;"}, false},
+		{"comments and blanks", "-- comment\n\nSELECT 1;\n  -- indented\nSELECT 2;", []string{"SELECT 1;", "SELECT 2;"}, false},
+		{"no trailing semicolon", "SELECT 1;\nSELECT 2", []string{"SELECT 1;", "SELECT 2"}, false},
+		{"inline semicolon", "SELECT ';' ; SELECT 2", []string{"SELECT ';' ; SELECT 2"}, false},
+		{"block", "SELECT 1;\n" + statementBegin + "\nCREATE FUNCTION f() AS $$\n-- kept\nBEGIN;\nEND;\n$$;\n" + statementEnd + "\nSELECT 2;",
+			[]string{"SELECT 1;", "CREATE FUNCTION f() AS $$\n-- kept\nBEGIN;\nEND;\n$$;", "SELECT 2;"}, false},
+		{"nested block", statementBegin + "\n" + statementBegin, nil, true},
+		{"end without begin", statementEnd, nil, true},
+		{"empty", "\n-- only comment\n", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitSql(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitSql() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("splitSql() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"github.com/succko/hera/global"
+)
+
+// ErrBrokerNotReady 消息代理未初始化，需启用 rocketmq 模块
+var ErrBrokerNotReady = errors.New("message broker not initialized")
+
+// Publish 通过配置的消息代理发布消息，可在 rocketmq 与进程内代理之间切换
+func Publish(ctx context.Context, topic string, body interface{}, opts ...SendOption) error {
+	if global.App.Broker == nil {
+		return ErrBrokerNotReady
+	}
+	msg, err := NewMessage(topic, body, opts...)
+	if err != nil {
+		return err
+	}
+	return global.App.Broker.Publish(ctx, msg)
+}
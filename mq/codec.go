@@ -0,0 +1,45 @@
+package mq
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+)
+
+// Codec 消息体编码方式
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// CodecFunc 将函数转换为 Codec
+type CodecFunc func(v interface{}) ([]byte, error)
+
+func (f CodecFunc) Marshal(v interface{}) ([]byte, error) {
+	return f(v)
+}
+
+var (
+	// JSON 使用 encoding/json 编码，默认编码方式
+	JSON Codec = CodecFunc(json.Marshal)
+
+	// Proto 使用 protobuf 编码，消息体需实现 proto.Message，如 pb.InnoPacket
+	Proto Codec = CodecFunc(func(v interface{}) ([]byte, error) {
+		m, ok := v.(proto.Message)
+		if !ok {
+			return nil, fmt.Errorf("mq proto codec: %T is not a proto.Message", v)
+		}
+		return proto.Marshal(m)
+	})
+
+	// Raw 不编码，消息体需为 []byte 或 string
+	Raw Codec = CodecFunc(func(v interface{}) ([]byte, error) {
+		switch b := v.(type) {
+		case []byte:
+			return b, nil
+		case string:
+			return []byte(b), nil
+		default:
+			return nil, fmt.Errorf("mq raw codec: %T is not []byte or string", v)
+		}
+	})
+)
@@ -0,0 +1,23 @@
+package mq
+
+import (
+	"context"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/succko/hera/broker"
+)
+
+// Handler 消息处理函数。返回错误或panic时消息稍后重试，超过最大重试次数后投递到死信主题
+type Handler = func(ctx context.Context, msg *primitive.MessageExt) error
+
+// Wrap 将只处理消息体的函数转换为 Handler，panic 时消息同样会重试
+func Wrap(f func(message []byte)) Handler {
+	return func(ctx context.Context, msg *primitive.MessageExt) error {
+		f(msg.Body)
+		return nil
+	}
+}
+
+// Handle 执行消息处理函数，将panic转换为错误
+func Handle(ctx context.Context, h Handler, msg *primitive.MessageExt) error {
+	return broker.Handle(ctx, h, msg)
+}
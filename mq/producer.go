@@ -2,40 +2,186 @@ package mq
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/apache/rocketmq-client-go/v2/primitive"
 	"github.com/succko/hera/global"
 	"go.uber.org/zap"
 )
 
+// ErrProducerNotReady 生产者未初始化，需启用 rocketmq 模块
+var ErrProducerNotReady = errors.New("rocketmq producer not initialized")
+
 type producer struct {
 }
 
 var Producer = new(producer)
 
-func (p *producer) SendSync(topic string, body interface{}) {
-	p.SendSyncWithTag(topic, body, "")
+// SendOption 发送选项
+type SendOption func(o *sendOptions)
+
+type sendOptions struct {
+	tag         string
+	keys        []string
+	properties  map[string]string
+	delayLevel  int
+	shardingKey string
+	codec       Codec
+}
+
+// WithTag 设置消息标签
+func WithTag(tag string) SendOption {
+	return func(o *sendOptions) {
+		o.tag = tag
+	}
+}
+
+// WithKeys 设置消息键，用于按键查询消息
+func WithKeys(keys ...string) SendOption {
+	return func(o *sendOptions) {
+		o.keys = append(o.keys, keys...)
+	}
+}
+
+// WithProperty 设置消息属性，可用于 SQL92 过滤
+func WithProperty(key, value string) SendOption {
+	return func(o *sendOptions) {
+		if o.properties == nil {
+			o.properties = make(map[string]string)
+		}
+		o.properties[key] = value
+	}
+}
+
+// WithDelayLevel 设置延时等级，1-18 分别对应 1s 5s 10s 30s 1m 2m 3m 4m 5m 6m 7m 8m 9m 10m 20m 30m 1h 2h
+func WithDelayLevel(level int) SendOption {
+	return func(o *sendOptions) {
+		o.delayLevel = level
+	}
+}
+
+// WithShardingKey 设置分区键，相同分区键的消息发送到同一队列，配合顺序消费保证顺序
+func WithShardingKey(key string) SendOption {
+	return func(o *sendOptions) {
+		o.shardingKey = key
+	}
+}
+
+// WithCodec 设置消息体编码方式，默认 JSON
+func WithCodec(c Codec) SendOption {
+	return func(o *sendOptions) {
+		o.codec = c
+	}
+}
+
+// NewMessage 按发送选项构造消息
+func NewMessage(topic string, body interface{}, opts ...SendOption) (*primitive.Message, error) {
+	o := &sendOptions{codec: JSON}
+	for _, opt := range opts {
+		opt(o)
+	}
+	data, err := o.codec.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	msg := primitive.NewMessage(topic, data)
+	if o.tag != "" {
+		msg.WithTag(o.tag)
+	}
+	if len(o.keys) > 0 {
+		msg.WithKeys(o.keys)
+	}
+	if len(o.properties) > 0 {
+		msg.WithProperties(o.properties)
+	}
+	if o.delayLevel > 0 {
+		msg.WithDelayTimeLevel(o.delayLevel)
+	}
+	if o.shardingKey != "" {
+		msg.WithShardingKey(o.shardingKey)
+	}
+	return msg, nil
+}
+
+// Send 同步发送消息，返回发送结果
+func (p *producer) Send(ctx context.Context, topic string, body interface{}, opts ...SendOption) (*primitive.SendResult, error) {
+	msg, err := NewMessage(topic, body, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if global.App.RocketMqProducer == nil {
+		return publish(ctx, msg)
+	}
+	res, err := global.App.RocketMqProducer.SendSync(ctx, msg)
+	if err != nil {
+		zap.L().Error("send message error", zap.String("topic", topic), zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("send message success", zap.String("topic", topic), zap.String("result", res.String()))
+	return res, nil
 }
 
-func (p *producer) SendSyncWithTag(topic string, body interface{}, tag string) {
-	data, _ := json.Marshal(body)
-	//实例化消息
-	msg := &primitive.Message{
-		Topic: topic,
-		Body:  data,
+// SendAsync 异步发送消息，发送完成后回调 callback
+func (p *producer) SendAsync(ctx context.Context, topic string, body interface{}, callback func(ctx context.Context, res *primitive.SendResult, err error), opts ...SendOption) error {
+	msg, err := NewMessage(topic, body, opts...)
+	if err != nil {
+		return err
+	}
+	done := func(ctx context.Context, res *primitive.SendResult, err error) {
+		if err != nil {
+			zap.L().Error("send async message error", zap.String("topic", topic), zap.Error(err))
+		}
+		if callback != nil {
+			callback(ctx, res, err)
+		}
 	}
-	msg.WithDelayTimeLevel(1)
-	if tag != "" {
-		msg.WithTag(tag)
+	if global.App.RocketMqProducer == nil {
+		if global.App.Broker == nil {
+			return ErrProducerNotReady
+		}
+		go func() {
+			res, err := publish(ctx, msg)
+			done(ctx, res, err)
+		}()
+		return nil
 	}
-	msg.WithKeys([]string{"DEFAULT"})
-	//msg.WithDelayTimeLevel(1)
-	//同步发送
-	res, err := global.App.RocketMqProducer.SendSync(context.Background(), msg)
+	return global.App.RocketMqProducer.SendAsync(ctx, done, msg)
+}
+
+// SendOneWay 单向发送消息，不等待 broker 响应
+func (p *producer) SendOneWay(ctx context.Context, topic string, body interface{}, opts ...SendOption) error {
+	msg, err := NewMessage(topic, body, opts...)
 	if err != nil {
-		zap.L().Error(fmt.Sprintf("send message error: %s", err))
-	} else {
-		zap.L().Info(fmt.Sprintf("send message success: result=%s", res.String()))
+		return err
+	}
+	if global.App.RocketMqProducer == nil {
+		_, err = publish(ctx, msg)
+		return err
+	}
+	return global.App.RocketMqProducer.SendOneWay(ctx, msg)
+}
+
+// SendOrderly 按分区键同步发送顺序消息
+func (p *producer) SendOrderly(ctx context.Context, topic string, shardingKey string, body interface{}, opts ...SendOption) (*primitive.SendResult, error) {
+	return p.Send(ctx, topic, body, append(opts, WithShardingKey(shardingKey))...)
+}
+
+// SendSync 以 JSON 同步发送消息
+func (p *producer) SendSync(topic string, body interface{}) (*primitive.SendResult, error) {
+	return p.Send(context.Background(), topic, body)
+}
+
+// SendSyncWithTag 以 JSON 同步发送带标签的消息
+func (p *producer) SendSyncWithTag(topic string, body interface{}, tag string) (*primitive.SendResult, error) {
+	return p.Send(context.Background(), topic, body, WithTag(tag))
+}
+
+// 未使用 RocketMQ 时通过消息代理发布，例如测试中的进程内代理
+func publish(ctx context.Context, msg *primitive.Message) (*primitive.SendResult, error) {
+	if global.App.Broker == nil {
+		return nil, ErrProducerNotReady
+	}
+	if err := global.App.Broker.Publish(ctx, msg); err != nil {
+		return nil, err
 	}
+	return &primitive.SendResult{Status: primitive.SendOK, MessageQueue: &primitive.MessageQueue{Topic: msg.Topic}}, nil
 }
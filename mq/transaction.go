@@ -0,0 +1,106 @@
+package mq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/succko/hera/global"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// 关联半消息与本地事务的消息属性
+const txProperty = "HERA_TX_ID"
+
+// ErrTransactionProducerNotReady 事务生产者未初始化，需注册事务回查函数
+var ErrTransactionProducerNotReady = errors.New("rocketmq transaction producer not initialized, register a transaction checker first")
+
+// Checker 事务回查函数，broker 未收到本地事务结果时调用，返回提交、回滚或未知
+type Checker = func(msg *primitive.MessageExt) primitive.LocalTransactionState
+
+// 正在执行的本地事务
+type localTx struct {
+	ctx context.Context
+	f   func(ctx context.Context) error
+	err error
+}
+
+// 正在执行的本地事务，键为 txProperty
+var pending sync.Map
+
+// transactionListener 执行随半消息发送的本地事务，并将回查交给注册的 Checker
+type transactionListener struct {
+	checker Checker
+}
+
+// NewTransactionListener 创建事务监听器
+func NewTransactionListener(checker Checker) primitive.TransactionListener {
+	return &transactionListener{checker: checker}
+}
+
+func (l *transactionListener) ExecuteLocalTransaction(msg *primitive.Message) primitive.LocalTransactionState {
+	v, ok := pending.Load(msg.GetProperty(txProperty))
+	if !ok {
+		return primitive.UnknowState
+	}
+	tx := v.(*localTx)
+	if tx.err = tx.f(tx.ctx); tx.err != nil {
+		return primitive.RollbackMessageState
+	}
+	return primitive.CommitMessageState
+}
+
+func (l *transactionListener) CheckLocalTransaction(msg *primitive.MessageExt) primitive.LocalTransactionState {
+	state := l.checker(msg)
+	zap.L().Info("check local transaction", zap.String("topic", msg.Topic), zap.String("msgId", msg.MsgId), zap.Int("state", int(state)))
+	return state
+}
+
+// SendTransaction 发送事务消息：半消息发送成功后执行本地事务 local，
+// local 返回 nil 时提交消息，返回错误时回滚消息并将错误返回给调用方。
+func (p *producer) SendTransaction(ctx context.Context, topic string, body interface{}, local func(ctx context.Context) error, opts ...SendOption) (*primitive.TransactionSendResult, error) {
+	msg, err := NewMessage(topic, body, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tp := global.App.RocketMqTransactionProducer
+	if tp == nil {
+		if global.App.RocketMqProducer != nil || global.App.Broker == nil {
+			return nil, ErrTransactionProducerNotReady
+		}
+		return sendLocalTransaction(ctx, msg, local)
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	id := hex.EncodeToString(b)
+	msg.WithProperty(txProperty, id)
+
+	tx := &localTx{ctx: ctx, f: local}
+	pending.Store(id, tx)
+	defer pending.Delete(id)
+
+	res, err := tp.SendMessageInTransaction(ctx, msg)
+	if err != nil {
+		zap.L().Error("send transaction message error", zap.String("topic", topic), zap.Error(err))
+		return nil, err
+	}
+	if tx.err != nil {
+		return res, tx.err
+	}
+	zap.L().Info("send transaction message success", zap.String("topic", topic), zap.String("result", res.String()))
+	return res, nil
+}
+
+// 未使用 RocketMQ 时先执行本地事务，成功后通过消息代理发布，没有半消息与回查
+func sendLocalTransaction(ctx context.Context, msg *primitive.Message, local func(ctx context.Context) error) (*primitive.TransactionSendResult, error) {
+	if err := local(ctx); err != nil {
+		return &primitive.TransactionSendResult{State: primitive.RollbackMessageState}, err
+	}
+	res, err := publish(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	return &primitive.TransactionSendResult{SendResult: res, State: primitive.CommitMessageState}, nil
+}
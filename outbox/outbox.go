@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/succko/hera/mq"
+	"gorm.io/gorm"
+)
+
+// 消息状态
+const (
+	StatusPending = 0 // 待发布
+	StatusSent    = 1 // 已发布
+	StatusFailed  = 2 // 超过最大发布次数，需人工处理
+)
+
+// Message 发件箱表，与业务数据在同一事务中写入，由 Relay 发布到 RocketMQ
+type Message struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement"`
+	Topic      string    `gorm:"size:255;not null"`
+	Body       []byte    `gorm:"not null"`
+	Properties string    `gorm:"type:text"` // 标签、键、延时等级等消息属性，JSON格式
+	Status     int       `gorm:"not null;default:0;index:idx_mq_outbox_status_next,priority:1"`
+	Attempts   int       `gorm:"not null;default:0"`
+	NextAt     time.Time `gorm:"not null;index:idx_mq_outbox_status_next,priority:2"` // 下次发布时间
+	LastError  string    `gorm:"size:1024"`
+	SentAt     *time.Time
+	CreatedAt  time.Time
+}
+
+func (Message) TableName() string {
+	return "mq_outbox"
+}
+
+// Migrate 自动迁移发件箱表
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Message{})
+}
+
+// Add 在调用方的事务 tx 中记录一条待发布的消息，事务提交后由 Relay 发布。
+// opts 与 mq.Producer.Send 相同，用于指定编码、标签、键、延时等级与分区键。
+func Add(tx *gorm.DB, topic string, body interface{}, opts ...mq.SendOption) error {
+	msg, err := mq.NewMessage(topic, body, opts...)
+	if err != nil {
+		return err
+	}
+	var properties string
+	if props := msg.GetProperties(); len(props) > 0 {
+		data, err := json.Marshal(props)
+		if err != nil {
+			return err
+		}
+		properties = string(data)
+	}
+	return tx.Create(&Message{
+		Topic:      topic,
+		Body:       msg.Body,
+		Properties: properties,
+		Status:     StatusPending,
+		NextAt:     time.Now(),
+	}).Error
+}
@@ -0,0 +1,262 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/succko/hera/config"
+	"github.com/succko/hera/global"
+	"github.com/succko/hera/lock"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 多副本之间互斥的 Redis 锁
+	lockName = "hera:outbox:relay"
+	// 单轮发布的最长时间
+	roundTimeout = 20 * time.Second
+
+	// 最长退避时间
+	maxBackoff = 10 * time.Minute
+
+	// 清理已发布消息的间隔及每次删除的最大条数
+	cleanupInterval = time.Hour
+	cleanupBatch    = 1000
+)
+
+// Options 发件箱发布选项
+type Options struct {
+	Interval    time.Duration // 轮询间隔，默认1s
+	Batch       int           // 每轮发布的最大条数，默认100
+	MaxAttempts int           // 最大发布次数，默认10
+	Backoff     time.Duration // 发布失败后的初始退避，默认1s，每次失败翻倍
+	Retention   time.Duration // 已发布消息的保留时长，默认72h
+}
+
+// OptionsFrom 从配置构造发布选项
+func OptionsFrom(c config.Outbox) Options {
+	return Options{
+		Interval:    time.Duration(c.Interval) * time.Millisecond,
+		Batch:       c.Batch,
+		MaxAttempts: c.MaxAttempts,
+		Backoff:     time.Duration(c.Backoff) * time.Millisecond,
+		Retention:   time.Duration(c.Retention) * time.Hour,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.Batch <= 0 {
+		o.Batch = 100
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 10
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = time.Second
+	}
+	if o.Retention <= 0 {
+		o.Retention = 72 * time.Hour
+	}
+	return o
+}
+
+// Relay 轮询发件箱表，将待发布的消息通过 global.App.Broker 发布并标记为已发布。
+// 启用 Redis 时通过 global.Locker() 保证多副本中同一时刻只有一个 Relay 在发布。
+type Relay struct {
+	db          *gorm.DB
+	opts        Options
+	quit        chan struct{}
+	done        chan struct{}
+	once        sync.Once
+	lastCleanup time.Time
+}
+
+// NewRelay 创建发件箱发布器
+func NewRelay(db *gorm.DB, opts Options) *Relay {
+	return &Relay{
+		db:   db,
+		opts: opts.withDefaults(),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Start 在后台开始轮询
+func (r *Relay) Start() {
+	go r.run()
+	zap.L().Info("outbox relay start", zap.Duration("interval", r.opts.Interval))
+	// 未启用 Redis 时没有副本间的互斥，多副本部署可能重复发布
+	if global.App.Redis == nil {
+		zap.L().Warn("outbox relay runs without a distributed lock, enable redis when deploying more than one replica")
+	}
+}
+
+// Stop 停止轮询，等待进行中的一轮完成或ctx超时
+func (r *Relay) Stop(ctx context.Context) error {
+	r.once.Do(func() {
+		close(r.quit)
+	})
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Relay) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.quit:
+			zap.L().Info("outbox relay stop")
+			return
+		case <-ticker.C:
+			r.tick()
+		}
+	}
+}
+
+// 执行一轮发布，持续发布直到没有到期的消息或达到锁的有效期
+func (r *Relay) tick() {
+	// 单轮耗时有上限，轮次之间释放锁以便其他副本接管
+	ctx, cancel := context.WithTimeout(context.Background(), roundTimeout)
+	defer cancel()
+	if global.App.Redis != nil {
+		l, err := global.Locker().Obtain(ctx, lockName)
+		if err != nil {
+			if !errors.Is(err, lock.ErrNotObtained) {
+				zap.L().Error("outbox relay lock error", zap.Error(err))
+			}
+			return
+		}
+		defer l.Release(context.Background())
+		// 锁丢失时立即停止发布，避免多个副本同时发布
+		go func() {
+			select {
+			case <-l.Lost():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	for ctx.Err() == nil {
+		n, err := r.Publish(ctx)
+		if err != nil {
+			zap.L().Error("outbox relay publish error", zap.Error(err))
+			break
+		}
+		if n < r.opts.Batch {
+			break
+		}
+	}
+	if time.Since(r.lastCleanup) >= cleanupInterval {
+		r.lastCleanup = time.Now()
+		if err := r.Cleanup(ctx); err != nil {
+			zap.L().Error("outbox relay cleanup error", zap.Error(err))
+		}
+	}
+}
+
+// Publish 发布一批到期的消息，返回本批消息的条数
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	if global.App.Broker == nil {
+		return 0, nil
+	}
+	var messages []Message
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_at <= ?", StatusPending, time.Now()).
+		Order("id").Limit(r.opts.Batch).Find(&messages).Error
+	if err != nil {
+		return 0, err
+	}
+	for i := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+		r.publish(ctx, &messages[i])
+	}
+	return len(messages), nil
+}
+
+func (r *Relay) publish(ctx context.Context, m *Message) {
+	msg := primitive.NewMessage(m.Topic, m.Body)
+	if m.Properties != "" {
+		var props map[string]string
+		if err := json.Unmarshal([]byte(m.Properties), &props); err == nil {
+			msg.WithProperties(props)
+		}
+	}
+	err := global.App.Broker.Publish(ctx, msg)
+	db := r.db.WithContext(ctx).Model(m)
+	if err == nil {
+		now := time.Now()
+		if err := db.Updates(map[string]interface{}{"status": StatusSent, "sent_at": now, "attempts": m.Attempts + 1}).Error; err != nil {
+			zap.L().Error("outbox relay mark sent error", zap.Uint64("id", m.ID), zap.Error(err))
+		}
+		return
+	}
+
+	attempts := m.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts, "last_error": truncate(err.Error(), 1024)}
+	if attempts >= r.opts.MaxAttempts {
+		updates["status"] = StatusFailed
+		zap.L().Error("outbox message failed", zap.Uint64("id", m.ID), zap.String("topic", m.Topic), zap.Int("attempts", attempts), zap.Error(err))
+	} else {
+		updates["next_at"] = time.Now().Add(backoff(r.opts.Backoff, attempts))
+		zap.L().Warn("outbox message retry", zap.Uint64("id", m.ID), zap.String("topic", m.Topic), zap.Int("attempts", attempts), zap.Error(err))
+	}
+	if err := db.Updates(updates).Error; err != nil {
+		zap.L().Error("outbox relay mark retry error", zap.Uint64("id", m.ID), zap.Error(err))
+	}
+}
+
+// Cleanup 删除超过保留时长的已发布消息
+func (r *Relay) Cleanup(ctx context.Context) error {
+	before := time.Now().Add(-r.opts.Retention)
+	for ctx.Err() == nil {
+		var ids []uint64
+		err := r.db.WithContext(ctx).Model(&Message{}).
+			Where("status = ? AND sent_at < ?", StatusSent, before).
+			Order("id").Limit(cleanupBatch).Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if err := r.db.WithContext(ctx).Delete(&Message{}, ids).Error; err != nil {
+			return err
+		}
+		if len(ids) < cleanupBatch {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// 第attempts次失败后的退避时间
+func backoff(base time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
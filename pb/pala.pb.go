@@ -25,6 +25,7 @@ type InnoPacket_PacketType int32
 const (
 	InnoPacket_TYPE_HEARTBEAT   InnoPacket_PacketType = 0
 	InnoPacket_TYPE_INSTRUCTION InnoPacket_PacketType = 1
+	InnoPacket_TYPE_ROOM        InnoPacket_PacketType = 2
 )
 
 // Enum value maps for InnoPacket_PacketType.
@@ -32,10 +33,12 @@ var (
 	InnoPacket_PacketType_name = map[int32]string{
 		0: "TYPE_HEARTBEAT",
 		1: "TYPE_INSTRUCTION",
+		2: "TYPE_ROOM",
 	}
 	InnoPacket_PacketType_value = map[string]int32{
 		"TYPE_HEARTBEAT":   0,
 		"TYPE_INSTRUCTION": 1,
+		"TYPE_ROOM":        2,
 	}
 )
 
@@ -121,6 +124,55 @@ func (InstructionPacket_ReportStatus) EnumDescriptor() ([]byte, []int) {
 	return file_proto_pala_proto_rawDescGZIP(), []int{2, 0}
 }
 
+type RoomPacket_Action int32
+
+const (
+	RoomPacket_ACTION_JOIN    RoomPacket_Action = 0
+	RoomPacket_ACTION_LEAVE   RoomPacket_Action = 1
+	RoomPacket_ACTION_MEMBERS RoomPacket_Action = 2
+)
+
+// Enum value maps for RoomPacket_Action.
+var (
+	RoomPacket_Action_name = map[int32]string{
+		0: "ACTION_JOIN",
+		1: "ACTION_LEAVE",
+		2: "ACTION_MEMBERS",
+	}
+	RoomPacket_Action_value = map[string]int32{
+		"ACTION_JOIN":    0,
+		"ACTION_LEAVE":   1,
+		"ACTION_MEMBERS": 2,
+	}
+)
+
+func (x RoomPacket_Action) Enum() *RoomPacket_Action {
+	p := new(RoomPacket_Action)
+	*p = x
+	return p
+}
+
+func (x RoomPacket_Action) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RoomPacket_Action) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_pala_proto_enumTypes[2].Descriptor()
+}
+
+func (RoomPacket_Action) Type() protoreflect.EnumType {
+	return &file_proto_pala_proto_enumTypes[2]
+}
+
+func (x RoomPacket_Action) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RoomPacket_Action.Descriptor instead.
+func (RoomPacket_Action) EnumDescriptor() ([]byte, []int) {
+	return file_proto_pala_proto_rawDescGZIP(), []int{3, 0}
+}
+
 type InnoPacket struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -128,9 +180,9 @@ type InnoPacket struct {
 
 	Type InnoPacket_PacketType `protobuf:"varint,1,opt,name=type,proto3,enum=pb.InnoPacket_PacketType" json:"type,omitempty"`
 	// Types that are assignable to Data:
-	//
 	//	*InnoPacket_HeartBeat
 	//	*InnoPacket_Instruction
+	//	*InnoPacket_Room
 	Data        isInnoPacket_Data `protobuf_oneof:"data"`
 	OfflineSend bool              `protobuf:"varint,4,opt,name=offlineSend,proto3" json:"offlineSend,omitempty"`
 }
@@ -195,6 +247,13 @@ func (x *InnoPacket) GetInstruction() *InstructionPacket {
 	return nil
 }
 
+func (x *InnoPacket) GetRoom() *RoomPacket {
+	if x, ok := x.GetData().(*InnoPacket_Room); ok {
+		return x.Room
+	}
+	return nil
+}
+
 func (x *InnoPacket) GetOfflineSend() bool {
 	if x != nil {
 		return x.OfflineSend
@@ -214,10 +273,16 @@ type InnoPacket_Instruction struct {
 	Instruction *InstructionPacket `protobuf:"bytes,3,opt,name=instruction,proto3,oneof"`
 }
 
+type InnoPacket_Room struct {
+	Room *RoomPacket `protobuf:"bytes,5,opt,name=room,proto3,oneof"`
+}
+
 func (*InnoPacket_HeartBeat) isInnoPacket_Data() {}
 
 func (*InnoPacket_Instruction) isInnoPacket_Data() {}
 
+func (*InnoPacket_Room) isInnoPacket_Data() {}
+
 type HeartBeatPacket struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -302,6 +367,8 @@ type InstructionPacket struct {
 	Code      string                         `protobuf:"bytes,6,opt,name=code,proto3" json:"code,omitempty"`
 	Body      string                         `protobuf:"bytes,7,opt,name=body,proto3" json:"body,omitempty"`
 	Report    InstructionPacket_ReportStatus `protobuf:"varint,8,opt,name=report,proto3,enum=pb.InstructionPacket_ReportStatus" json:"report,omitempty"`
+	TenantId  int64                          `protobuf:"varint,9,opt,name=tenantId,proto3" json:"tenantId,omitempty"`
+	RoomId    string                         `protobuf:"bytes,10,opt,name=roomId,proto3" json:"roomId,omitempty"`
 }
 
 func (x *InstructionPacket) Reset() {
@@ -392,19 +459,97 @@ func (x *InstructionPacket) GetReport() InstructionPacket_ReportStatus {
 	return InstructionPacket_STATUS_SEND
 }
 
+func (x *InstructionPacket) GetTenantId() int64 {
+	if x != nil {
+		return x.TenantId
+	}
+	return 0
+}
+
+func (x *InstructionPacket) GetRoomId() string {
+	if x != nil {
+		return x.RoomId
+	}
+	return ""
+}
+
+type RoomPacket struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Action  RoomPacket_Action `protobuf:"varint,1,opt,name=action,proto3,enum=pb.RoomPacket_Action" json:"action,omitempty"`
+	RoomId  string            `protobuf:"bytes,2,opt,name=roomId,proto3" json:"roomId,omitempty"`
+	Members []string          `protobuf:"bytes,3,rep,name=members,proto3" json:"members,omitempty"`
+}
+
+func (x *RoomPacket) Reset() {
+	*x = RoomPacket{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_pala_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RoomPacket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomPacket) ProtoMessage() {}
+
+func (x *RoomPacket) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pala_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomPacket.ProtoReflect.Descriptor instead.
+func (*RoomPacket) Descriptor() ([]byte, []int) {
+	return file_proto_pala_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RoomPacket) GetAction() RoomPacket_Action {
+	if x != nil {
+		return x.Action
+	}
+	return RoomPacket_ACTION_JOIN
+}
+
+func (x *RoomPacket) GetRoomId() string {
+	if x != nil {
+		return x.RoomId
+	}
+	return ""
+}
+
+func (x *RoomPacket) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
 type Response struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Code    int32  `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=Message,proto3" json:"Message,omitempty"`
+	Code      int32  `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty"`
+	Message   string `protobuf:"bytes,2,opt,name=Message,proto3" json:"Message,omitempty"`
+	RequestId string `protobuf:"bytes,3,opt,name=RequestId,proto3" json:"RequestId,omitempty"`
 }
 
 func (x *Response) Reset() {
 	*x = Response{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_pala_proto_msgTypes[3]
+		mi := &file_proto_pala_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -417,7 +562,7 @@ func (x *Response) String() string {
 func (*Response) ProtoMessage() {}
 
 func (x *Response) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_pala_proto_msgTypes[3]
+	mi := &file_proto_pala_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -430,7 +575,7 @@ func (x *Response) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Response.ProtoReflect.Descriptor instead.
 func (*Response) Descriptor() ([]byte, []int) {
-	return file_proto_pala_proto_rawDescGZIP(), []int{3}
+	return file_proto_pala_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Response) GetCode() int32 {
@@ -447,11 +592,112 @@ func (x *Response) GetMessage() string {
 	return ""
 }
 
+func (x *Response) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type BatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Packets []*InnoPacket `protobuf:"bytes,1,rep,name=packets,proto3" json:"packets,omitempty"`
+}
+
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_pala_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchRequest) ProtoMessage() {}
+
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pala_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_pala_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchRequest) GetPackets() []*InnoPacket {
+	if x != nil {
+		return x.Packets
+	}
+	return nil
+}
+
+type ReportRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TenantId int64 `protobuf:"varint,1,opt,name=tenantId,proto3" json:"tenantId,omitempty"`
+}
+
+func (x *ReportRequest) Reset() {
+	*x = ReportRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_pala_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportRequest) ProtoMessage() {}
+
+func (x *ReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pala_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportRequest.ProtoReflect.Descriptor instead.
+func (*ReportRequest) Descriptor() ([]byte, []int) {
+	return file_proto_pala_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ReportRequest) GetTenantId() int64 {
+	if x != nil {
+		return x.TenantId
+	}
+	return 0
+}
+
 var File_proto_pala_proto protoreflect.FileDescriptor
 
 var file_proto_pala_proto_rawDesc = []byte{
 	0x0a, 0x10, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x70, 0x61, 0x6c, 0x61, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x22, 0x8d, 0x02, 0x0a, 0x0a, 0x49, 0x6e, 0x6e, 0x6f, 0x50,
+	0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x22, 0xc2, 0x02, 0x0a, 0x0a, 0x49, 0x6e, 0x6e, 0x6f, 0x50,
 	0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x2d, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x70, 0x62, 0x2e, 0x49, 0x6e, 0x6e, 0x6f, 0x50, 0x61, 0x63,
 	0x6b, 0x65, 0x74, 0x2e, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04,
@@ -462,48 +708,81 @@ var file_proto_pala_proto_rawDesc = []byte{
 	0x74, 0x72, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15,
 	0x2e, 0x70, 0x62, 0x2e, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x50,
 	0x61, 0x63, 0x6b, 0x65, 0x74, 0x48, 0x00, 0x52, 0x0b, 0x69, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x6f, 0x66, 0x66, 0x6c, 0x69, 0x6e, 0x65, 0x53,
-	0x65, 0x6e, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x6f, 0x66, 0x66, 0x6c, 0x69,
-	0x6e, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x22, 0x36, 0x0a, 0x0a, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74,
-	0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x0e, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x48, 0x45, 0x41,
-	0x52, 0x54, 0x42, 0x45, 0x41, 0x54, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x54, 0x59, 0x50, 0x45,
-	0x5f, 0x49, 0x4e, 0x53, 0x54, 0x52, 0x55, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x10, 0x01, 0x42, 0x06,
-	0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x61, 0x0a, 0x0f, 0x48, 0x65, 0x61, 0x72, 0x74, 0x42,
-	0x65, 0x61, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x74, 0x65, 0x6e,
-	0x61, 0x6e, 0x74, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x74, 0x65, 0x6e,
-	0x61, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x03, 0x52, 0x02, 0x74, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x67, 0x6e, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x69, 0x67, 0x6e, 0x22, 0xda, 0x02, 0x0a, 0x11, 0x49, 0x6e,
-	0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12,
-	0x1c, 0x0a, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x64, 0x12, 0x10, 0x0a,
-	0x03, 0x70, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x70, 0x69, 0x64, 0x12,
-	0x16, 0x0a, 0x06, 0x66, 0x72, 0x6f, 0x6d, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x06, 0x66, 0x72, 0x6f, 0x6d, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x6f, 0x49, 0x64, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x6f, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x74,
-	0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
-	0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64,
-	0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a,
-	0x04, 0x62, 0x6f, 0x64, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x62, 0x6f, 0x64,
-	0x79, 0x12, 0x3a, 0x0a, 0x06, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28,
-	0x0e, 0x32, 0x22, 0x2e, 0x70, 0x62, 0x2e, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x69,
-	0x6f, 0x6e, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53,
-	0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x22, 0x67, 0x0a,
-	0x0c, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0f, 0x0a,
-	0x0b, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x53, 0x45, 0x4e, 0x44, 0x10, 0x00, 0x12, 0x0e,
-	0x0a, 0x0a, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x41, 0x43, 0x4b, 0x10, 0x01, 0x12, 0x10,
-	0x0a, 0x0c, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x52, 0x45, 0x54, 0x52, 0x59, 0x10, 0x02,
-	0x12, 0x12, 0x0a, 0x0e, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x4f, 0x46, 0x46, 0x4c, 0x49,
-	0x4e, 0x45, 0x10, 0x03, 0x12, 0x10, 0x0a, 0x0c, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x45,
-	0x52, 0x52, 0x4f, 0x52, 0x10, 0x04, 0x22, 0x38, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x43, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x04, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
-	0x32, 0x2e, 0x0a, 0x04, 0x50, 0x61, 0x6c, 0x61, 0x12, 0x26, 0x0a, 0x04, 0x53, 0x65, 0x6e, 0x64,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x48, 0x00, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x20, 0x0a, 0x0b, 0x6f, 0x66,
+	0x66, 0x6c, 0x69, 0x6e, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0b, 0x6f, 0x66, 0x66, 0x6c, 0x69, 0x6e, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x22, 0x45, 0x0a, 0x0a,
+	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x0e, 0x54, 0x59,
+	0x50, 0x45, 0x5f, 0x48, 0x45, 0x41, 0x52, 0x54, 0x42, 0x45, 0x41, 0x54, 0x10, 0x00, 0x12, 0x14,
+	0x0a, 0x10, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x49, 0x4e, 0x53, 0x54, 0x52, 0x55, 0x43, 0x54, 0x49,
+	0x4f, 0x4e, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x52, 0x4f, 0x4f,
+	0x4d, 0x10, 0x02, 0x42, 0x06, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x61, 0x0a, 0x0f, 0x48,
+	0x65, 0x61, 0x72, 0x74, 0x42, 0x65, 0x61, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1a,
+	0x0a, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x74, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69,
+	0x67, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x69, 0x67, 0x6e, 0x22, 0x8e,
+	0x03, 0x0a, 0x11, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x50, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x70, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x70, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x72, 0x6f, 0x6d, 0x49, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66, 0x72, 0x6f, 0x6d, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04,
+	0x74, 0x6f, 0x49, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x6f, 0x49, 0x64,
+	0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x12,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x12, 0x3a, 0x0a, 0x06, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x22, 0x2e, 0x70, 0x62, 0x2e, 0x49, 0x6e, 0x73, 0x74,
+	0x72, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x72, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x16,
+	0x0a, 0x06, 0x72, 0x6f, 0x6f, 0x6d, 0x49, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x72, 0x6f, 0x6f, 0x6d, 0x49, 0x64, 0x22, 0x67, 0x0a, 0x0c, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0f, 0x0a, 0x0b, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53,
+	0x5f, 0x53, 0x45, 0x4e, 0x44, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x53, 0x54, 0x41, 0x54, 0x55,
+	0x53, 0x5f, 0x41, 0x43, 0x4b, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x53, 0x54, 0x41, 0x54, 0x55,
+	0x53, 0x5f, 0x52, 0x45, 0x54, 0x52, 0x59, 0x10, 0x02, 0x12, 0x12, 0x0a, 0x0e, 0x53, 0x54, 0x41,
+	0x54, 0x55, 0x53, 0x5f, 0x4f, 0x46, 0x46, 0x4c, 0x49, 0x4e, 0x45, 0x10, 0x03, 0x12, 0x10, 0x0a,
+	0x0c, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x04, 0x22,
+	0xae, 0x01, 0x0a, 0x0a, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x2d,
+	0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15,
+	0x2e, 0x70, 0x62, 0x2e, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x41,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a,
+	0x06, 0x72, 0x6f, 0x6f, 0x6d, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72,
+	0x6f, 0x6f, 0x6d, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x22,
+	0x3f, 0x0a, 0x06, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x43, 0x54,
+	0x49, 0x4f, 0x4e, 0x5f, 0x4a, 0x4f, 0x49, 0x4e, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x43,
+	0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x4c, 0x45, 0x41, 0x56, 0x45, 0x10, 0x01, 0x12, 0x12, 0x0a, 0x0e,
+	0x41, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x4d, 0x45, 0x4d, 0x42, 0x45, 0x52, 0x53, 0x10, 0x02,
+	0x22, 0x56, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x43, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x43, 0x6f, 0x64, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x64, 0x22, 0x38, 0x0a, 0x0c, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x28, 0x0a, 0x07, 0x70, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x62, 0x2e, 0x49,
+	0x6e, 0x6e, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x07, 0x70, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x73, 0x22, 0x2b, 0x0a, 0x0d, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x32,
+	0x91, 0x01, 0x0a, 0x04, 0x50, 0x61, 0x6c, 0x61, 0x12, 0x26, 0x0a, 0x04, 0x53, 0x65, 0x6e, 0x64,
 	0x12, 0x0e, 0x2e, 0x70, 0x62, 0x2e, 0x49, 0x6e, 0x6e, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74,
 	0x1a, 0x0c, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
-	0x42, 0x06, 0x5a, 0x04, 0x2e, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x12, 0x2f, 0x0a, 0x09, 0x53, 0x65, 0x6e, 0x64, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x10, 0x2e,
+	0x70, 0x62, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x0c, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x30,
+	0x01, 0x12, 0x30, 0x0a, 0x07, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x12, 0x11, 0x2e, 0x70,
+	0x62, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x0e, 0x2e, 0x70, 0x62, 0x2e, 0x49, 0x6e, 0x6e, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x22,
+	0x00, 0x30, 0x01, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -518,28 +797,39 @@ func file_proto_pala_proto_rawDescGZIP() []byte {
 	return file_proto_pala_proto_rawDescData
 }
 
-var file_proto_pala_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_proto_pala_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_pala_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_proto_pala_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_proto_pala_proto_goTypes = []interface{}{
 	(InnoPacket_PacketType)(0),          // 0: pb.InnoPacket.PacketType
 	(InstructionPacket_ReportStatus)(0), // 1: pb.InstructionPacket.ReportStatus
-	(*InnoPacket)(nil),                  // 2: pb.InnoPacket
-	(*HeartBeatPacket)(nil),             // 3: pb.HeartBeatPacket
-	(*InstructionPacket)(nil),           // 4: pb.InstructionPacket
-	(*Response)(nil),                    // 5: pb.Response
+	(RoomPacket_Action)(0),              // 2: pb.RoomPacket.Action
+	(*InnoPacket)(nil),                  // 3: pb.InnoPacket
+	(*HeartBeatPacket)(nil),             // 4: pb.HeartBeatPacket
+	(*InstructionPacket)(nil),           // 5: pb.InstructionPacket
+	(*RoomPacket)(nil),                  // 6: pb.RoomPacket
+	(*Response)(nil),                    // 7: pb.Response
+	(*BatchRequest)(nil),                // 8: pb.BatchRequest
+	(*ReportRequest)(nil),               // 9: pb.ReportRequest
 }
 var file_proto_pala_proto_depIdxs = []int32{
-	0, // 0: pb.InnoPacket.type:type_name -> pb.InnoPacket.PacketType
-	3, // 1: pb.InnoPacket.heartBeat:type_name -> pb.HeartBeatPacket
-	4, // 2: pb.InnoPacket.instruction:type_name -> pb.InstructionPacket
-	1, // 3: pb.InstructionPacket.report:type_name -> pb.InstructionPacket.ReportStatus
-	2, // 4: pb.Pala.Send:input_type -> pb.InnoPacket
-	5, // 5: pb.Pala.Send:output_type -> pb.Response
-	5, // [5:6] is the sub-list for method output_type
-	4, // [4:5] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	0,  // 0: pb.InnoPacket.type:type_name -> pb.InnoPacket.PacketType
+	4,  // 1: pb.InnoPacket.heartBeat:type_name -> pb.HeartBeatPacket
+	5,  // 2: pb.InnoPacket.instruction:type_name -> pb.InstructionPacket
+	6,  // 3: pb.InnoPacket.room:type_name -> pb.RoomPacket
+	1,  // 4: pb.InstructionPacket.report:type_name -> pb.InstructionPacket.ReportStatus
+	2,  // 5: pb.RoomPacket.action:type_name -> pb.RoomPacket.Action
+	3,  // 6: pb.BatchRequest.packets:type_name -> pb.InnoPacket
+	3,  // 7: pb.Pala.Send:input_type -> pb.InnoPacket
+	8,  // 8: pb.Pala.SendBatch:input_type -> pb.BatchRequest
+	9,  // 9: pb.Pala.Reports:input_type -> pb.ReportRequest
+	7,  // 10: pb.Pala.Send:output_type -> pb.Response
+	7,  // 11: pb.Pala.SendBatch:output_type -> pb.Response
+	3,  // 12: pb.Pala.Reports:output_type -> pb.InnoPacket
+	10, // [10:13] is the sub-list for method output_type
+	7,  // [7:10] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_proto_pala_proto_init() }
@@ -585,6 +875,18 @@ func file_proto_pala_proto_init() {
 			}
 		}
 		file_proto_pala_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RoomPacket); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_pala_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Response); i {
 			case 0:
 				return &v.state
@@ -596,18 +898,43 @@ func file_proto_pala_proto_init() {
 				return nil
 			}
 		}
+		file_proto_pala_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_pala_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReportRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_proto_pala_proto_msgTypes[0].OneofWrappers = []interface{}{
 		(*InnoPacket_HeartBeat)(nil),
 		(*InnoPacket_Instruction)(nil),
+		(*InnoPacket_Room)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_pala_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   4,
+			NumEnums:      3,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,6 +23,8 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type PalaClient interface {
 	Send(ctx context.Context, in *InnoPacket, opts ...grpc.CallOption) (*Response, error)
+	SendBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (Pala_SendBatchClient, error)
+	Reports(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (Pala_ReportsClient, error)
 }
 
 type palaClient struct {
@@ -42,11 +44,77 @@ func (c *palaClient) Send(ctx context.Context, in *InnoPacket, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *palaClient) SendBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (Pala_SendBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Pala_ServiceDesc.Streams[0], "/pb.Pala/SendBatch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &palaSendBatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Pala_SendBatchClient interface {
+	Recv() (*Response, error)
+	grpc.ClientStream
+}
+
+type palaSendBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *palaSendBatchClient) Recv() (*Response, error) {
+	m := new(Response)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *palaClient) Reports(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (Pala_ReportsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Pala_ServiceDesc.Streams[1], "/pb.Pala/Reports", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &palaReportsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Pala_ReportsClient interface {
+	Recv() (*InnoPacket, error)
+	grpc.ClientStream
+}
+
+type palaReportsClient struct {
+	grpc.ClientStream
+}
+
+func (x *palaReportsClient) Recv() (*InnoPacket, error) {
+	m := new(InnoPacket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // PalaServer is the server API for Pala service.
 // All implementations must embed UnimplementedPalaServer
 // for forward compatibility
 type PalaServer interface {
 	Send(context.Context, *InnoPacket) (*Response, error)
+	SendBatch(*BatchRequest, Pala_SendBatchServer) error
+	Reports(*ReportRequest, Pala_ReportsServer) error
 	mustEmbedUnimplementedPalaServer()
 }
 
@@ -57,6 +125,12 @@ type UnimplementedPalaServer struct {
 func (UnimplementedPalaServer) Send(context.Context, *InnoPacket) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Send not implemented")
 }
+func (UnimplementedPalaServer) SendBatch(*BatchRequest, Pala_SendBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method SendBatch not implemented")
+}
+func (UnimplementedPalaServer) Reports(*ReportRequest, Pala_ReportsServer) error {
+	return status.Errorf(codes.Unimplemented, "method Reports not implemented")
+}
 func (UnimplementedPalaServer) mustEmbedUnimplementedPalaServer() {}
 
 // UnsafePalaServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +162,48 @@ func _Pala_Send_Handler(srv interface{}, ctx context.Context, dec func(interface
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Pala_SendBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PalaServer).SendBatch(m, &palaSendBatchServer{stream})
+}
+
+type Pala_SendBatchServer interface {
+	Send(*Response) error
+	grpc.ServerStream
+}
+
+type palaSendBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *palaSendBatchServer) Send(m *Response) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Pala_Reports_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PalaServer).Reports(m, &palaReportsServer{stream})
+}
+
+type Pala_ReportsServer interface {
+	Send(*InnoPacket) error
+	grpc.ServerStream
+}
+
+type palaReportsServer struct {
+	grpc.ServerStream
+}
+
+func (x *palaReportsServer) Send(m *InnoPacket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Pala_ServiceDesc is the grpc.ServiceDesc for Pala service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -100,6 +216,17 @@ var Pala_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Pala_Send_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SendBatch",
+			Handler:       _Pala_SendBatch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Reports",
+			Handler:       _Pala_Reports_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "proto/pala.proto",
 }
@@ -0,0 +1,33 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header 传递追踪ID的请求头，请求未携带时由服务端生成并在响应中返回
+const Header = "X-Request-Id"
+
+type key struct{}
+
+// NewContext 返回携带追踪ID的上下文
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// FromContext 上下文中的追踪ID，没有时返回空字符串
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// NewID 生成随机的追踪ID
+func NewID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
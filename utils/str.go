@@ -2,14 +2,12 @@ package utils
 
 import (
 	"math/rand"
-	"time"
 )
 
 func RandString(len int) string {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	bytes := make([]byte, len)
 	for i := 0; i < len; i++ {
-		b := r.Intn(26) + 65
+		b := rand.Intn(26) + 65
 		bytes[i] = byte(b)
 	}
 	return string(bytes)
@@ -0,0 +1,147 @@
+package ws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/succko/hera/pb"
+	"go.uber.org/zap"
+)
+
+// 确认与重试的默认配置
+const (
+	defaultAckMaxRetries = 3
+	defaultAckBackoff    = 2 * time.Second
+	ackScanPeriod        = 500 * time.Millisecond
+)
+
+// AckOptions 指令确认与重试选项
+type AckOptions struct {
+	MaxRetries int           // 未确认时的最大重发次数，默认 3
+	Backoff    time.Duration // 首次重发前的等待时间，之后每次翻倍，默认 2 秒
+}
+
+// 等待确认的指令
+type pendingInstruction struct {
+	toId     string
+	packet   *pb.InnoPacket
+	backend  bool // 发送方是否为服务端
+	attempts int  // 已重发次数
+	deadline time.Time
+}
+
+// acker 跟踪已投递但未确认的指令，超时未收到接收方的 STATUS_ACK 时按退避重发，
+// 超过重发次数后报告 STATUS_ERROR。重发时 requestId 保持不变，接收方据此去重。
+type acker struct {
+	hub     *Hub
+	opts    AckOptions
+	mu      sync.Mutex
+	pending map[string]*pendingInstruction
+}
+
+// EnableAck 开启指令确认与重试，仅跟踪带 requestId 的指令，需在 Run 之前调用。
+func (h *Hub) EnableAck(opts AckOptions) {
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = defaultAckMaxRetries
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultAckBackoff
+	}
+	h.acker = &acker{
+		hub:     h,
+		opts:    opts,
+		pending: make(map[string]*pendingInstruction),
+	}
+	go h.acker.run()
+}
+
+func ackKey(toId, requestId string) string {
+	return toId + "\x00" + requestId
+}
+
+// 跟踪已投递的指令，并向发送方报告 STATUS_SEND
+func (a *acker) track(toId string, innoPacket *pb.InnoPacket, backend bool) {
+	instruction := innoPacket.GetInstruction()
+	if instruction.GetRequestId() == "" || instruction.GetReport() != pb.InstructionPacket_STATUS_SEND {
+		return
+	}
+	a.mu.Lock()
+	a.pending[ackKey(toId, instruction.GetRequestId())] = &pendingInstruction{
+		toId:     toId,
+		packet:   innoPacket,
+		backend:  backend,
+		deadline: time.Now().Add(a.opts.Backoff),
+	}
+	a.mu.Unlock()
+	reportStatus(innoPacket, pb.InstructionPacket_STATUS_SEND, backend)
+}
+
+// 收到接收方的确认，返回是否存在对应的指令
+func (a *acker) ack(toId, requestId string) bool {
+	a.mu.Lock()
+	p, ok := a.pending[ackKey(toId, requestId)]
+	delete(a.pending, ackKey(toId, requestId))
+	a.mu.Unlock()
+	if ok {
+		reportStatus(p.packet, pb.InstructionPacket_STATUS_ACK, p.backend)
+	}
+	return ok
+}
+
+// 处理接收方的确认，集群模式下指令可能由其他节点投递，本节点未找到时转发给其他节点
+func (h *Hub) ackReceived(toId, requestId string) {
+	if requestId == "" {
+		return
+	}
+	if !h.acker.ack(toId, requestId) && h.cluster != nil {
+		h.cluster.forwardAck(toId, requestId)
+	}
+}
+
+func (a *acker) run() {
+	ticker := time.NewTicker(ackScanPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.retry()
+		case <-a.hub.quit:
+			return
+		}
+	}
+}
+
+// 重发到期的指令
+func (a *acker) retry() {
+	now := time.Now()
+	var due []*pendingInstruction
+	a.mu.Lock()
+	for key, p := range a.pending {
+		if p.deadline.After(now) {
+			continue
+		}
+		if p.attempts >= a.opts.MaxRetries {
+			delete(a.pending, key)
+			go reportStatus(p.packet, pb.InstructionPacket_STATUS_ERROR, p.backend)
+			continue
+		}
+		p.attempts++
+		p.deadline = now.Add(a.opts.Backoff << p.attempts)
+		due = append(due, p)
+	}
+	a.mu.Unlock()
+
+	for _, p := range due {
+		zap.L().Info("ack retry instruction", zap.String("uuid", p.toId), zap.String("requestId", p.packet.GetInstruction().GetRequestId()), zap.Int("attempts", p.attempts))
+		if sendMessage(p.toId, p.packet) {
+			reportStatus(p.packet, pb.InstructionPacket_STATUS_RETRY, p.backend)
+			continue
+		}
+		// 接收方已离线，转为离线消息
+		a.mu.Lock()
+		delete(a.pending, ackKey(p.toId, p.packet.GetInstruction().GetRequestId()))
+		a.mu.Unlock()
+		a.hub.saveOffline(p.toId, p.packet)
+		reportStatus(p.packet, pb.InstructionPacket_STATUS_OFFLINE, p.backend)
+	}
+}
@@ -0,0 +1,259 @@
+package ws
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/succko/hera/pb"
+	"github.com/succko/hera/utils"
+)
+
+// 认证的默认配置
+const (
+	defaultAuthSkew  = 5 * time.Minute
+	defaultAuthGrace = 10 * time.Second
+)
+
+var (
+	ErrAuthExpired = errors.New("ws auth: timestamp outside skew window")
+	ErrAuthSign    = errors.New("ws auth: invalid sign")
+	ErrAuthReplay  = errors.New("ws auth: replayed heartbeat")
+)
+
+// Authenticator 校验客户端心跳包，返回nil表示认证成功。
+type Authenticator interface {
+	Authenticate(ctx context.Context, heartBeat *pb.HeartBeatPacket) error
+}
+
+// SecretResolver 根据租户获取签名密钥。
+type SecretResolver func(tenantId int64) (string, error)
+
+// StaticSecrets 按租户查找密钥，未配置的租户使用默认密钥。
+func StaticSecrets(defaultSecret string, tenants map[int64]string) SecretResolver {
+	return func(tenantId int64) (string, error) {
+		if secret, ok := tenants[tenantId]; ok {
+			return secret, nil
+		}
+		if defaultSecret == "" {
+			return "", fmt.Errorf("ws auth: no secret for tenant %d", tenantId)
+		}
+		return defaultSecret, nil
+	}
+}
+
+// NonceStore 记录已使用的一次性标识，防止心跳包被重放。
+type NonceStore interface {
+	// Use 标记nonce为已使用，返回false表示nonce在有效期内已被使用过。
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// AuthOptions 认证选项
+type AuthOptions struct {
+	Skew   time.Duration // 允许的时钟偏差，默认 5 分钟
+	Nonces NonceStore    // 为nil时不做重放保护
+}
+
+func (o AuthOptions) withDefaults() AuthOptions {
+	if o.Skew <= 0 {
+		o.Skew = defaultAuthSkew
+	}
+	return o
+}
+
+// SetAuthenticator 设置认证方式，需在 Run 之前调用。
+func (h *Hub) SetAuthenticator(authenticator Authenticator, grace time.Duration) {
+	if grace <= 0 {
+		grace = defaultAuthGrace
+	}
+	h.authenticator = authenticator
+	h.authGrace = grace
+}
+
+// Authenticator 当前的认证方式
+func (h *Hub) Authenticator() Authenticator {
+	return h.authenticator
+}
+
+// signAuthenticator 校验 sign = f(secret, id, ts)，ts 为秒级时间戳
+type signAuthenticator struct {
+	sign    func(secret, id string, ts int64) string
+	secrets SecretResolver
+	opts    AuthOptions
+}
+
+// NewHmacAuthenticator 创建 HMAC-SHA256 认证：sign = hex(HMAC-SHA256(secret, id+ts))
+func NewHmacAuthenticator(secrets SecretResolver, opts AuthOptions) Authenticator {
+	return &signAuthenticator{
+		sign: func(secret, id string, ts int64) string {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(id + strconv.FormatInt(ts, 10)))
+			return hex.EncodeToString(mac.Sum(nil))
+		},
+		secrets: secrets,
+		opts:    opts.withDefaults(),
+	}
+}
+
+// NewMd5Authenticator 创建兼容旧版客户端的认证：sign = MD5(id+ts+secret)
+func NewMd5Authenticator(secrets SecretResolver, opts AuthOptions) Authenticator {
+	return &signAuthenticator{
+		sign: func(secret, id string, ts int64) string {
+			return utils.MD5([]byte(id + strconv.FormatInt(ts, 10) + secret))
+		},
+		secrets: secrets,
+		opts:    opts.withDefaults(),
+	}
+}
+
+func (a *signAuthenticator) Authenticate(ctx context.Context, heartBeat *pb.HeartBeatPacket) error {
+	ts := time.Unix(heartBeat.GetTs(), 0)
+	if d := time.Since(ts); d > a.opts.Skew || d < -a.opts.Skew {
+		return ErrAuthExpired
+	}
+	secret, err := a.secrets(heartBeat.GetTenantId())
+	if err != nil {
+		return err
+	}
+	sign := a.sign(secret, heartBeat.GetId(), heartBeat.GetTs())
+	if !hmac.Equal([]byte(sign), []byte(heartBeat.GetSign())) {
+		return ErrAuthSign
+	}
+	// 签名在时钟偏差窗口内有效，同一签名只能使用一次
+	return useNonce(ctx, a.opts.Nonces, "sign:"+heartBeat.GetSign(), 2*a.opts.Skew)
+}
+
+// jwtAuthenticator 校验 sign 中携带的 HS256 JWT，sub 需与心跳包 id 一致
+type jwtAuthenticator struct {
+	secrets SecretResolver
+	opts    AuthOptions
+}
+
+// NewJwtAuthenticator 创建 JWT 认证，token 放在 HeartBeatPacket.sign 中。
+// sub 必须与 id 一致；携带 tenant_id 声明时必须与 tenantId 一致；携带 jti 时用于重放保护。
+func NewJwtAuthenticator(secrets SecretResolver, opts AuthOptions) Authenticator {
+	return &jwtAuthenticator{
+		secrets: secrets,
+		opts:    opts.withDefaults(),
+	}
+}
+
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, heartBeat *pb.HeartBeatPacket) error {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(heartBeat.GetSign(), claims, func(token *jwt.Token) (interface{}, error) {
+		secret, err := a.secrets(heartBeat.GetTenantId())
+		if err != nil {
+			return nil, err
+		}
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}), jwt.WithLeeway(a.opts.Skew), jwt.WithExpirationRequired())
+	if err != nil {
+		return fmt.Errorf("ws auth: %w", err)
+	}
+	if sub, _ := claims.GetSubject(); sub != heartBeat.GetId() {
+		return ErrAuthSign
+	}
+	if tenant, ok := claims["tenant_id"]; ok && !tenantMatches(tenant, heartBeat.GetTenantId()) {
+		return ErrAuthSign
+	}
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		exp, _ := claims.GetExpirationTime()
+		return useNonce(ctx, a.opts.Nonces, "jti:"+jti, time.Until(exp.Time)+a.opts.Skew)
+	}
+	return nil
+}
+
+// tenant_id 声明是否与租户一致，JSON 数字解析为 float64，也可能为 json.Number 或字符串
+func tenantMatches(claim interface{}, tenantId int64) bool {
+	switch v := claim.(type) {
+	case float64:
+		return v == math.Trunc(v) && v == float64(tenantId)
+	case json.Number:
+		n, err := v.Int64()
+		return err == nil && n == tenantId
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return err == nil && n == tenantId
+	default:
+		return false
+	}
+}
+
+// 已认证连接的后续心跳
+type reauthKey struct{}
+
+// Reauthenticating 是否为已认证连接的后续心跳。客户端会重复发送同一心跳包以保持在线，
+// 此时不做重放检查，自定义认证应同样跳过
+func Reauthenticating(ctx context.Context) bool {
+	v, _ := ctx.Value(reauthKey{}).(bool)
+	return v
+}
+
+func useNonce(ctx context.Context, nonces NonceStore, nonce string, ttl time.Duration) error {
+	if nonces == nil || Reauthenticating(ctx) {
+		return nil
+	}
+	ok, err := nonces.Use(ctx, nonce, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAuthReplay
+	}
+	return nil
+}
+
+// memoryNonceStore 进程内的nonce记录，适用于单节点部署
+type memoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewMemoryNonceStore 创建进程内的nonce记录
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *memoryNonceStore) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if expireAt, ok := s.nonces[nonce]; ok && expireAt.After(now) {
+		return false, nil
+	}
+	// 记录数量较多时清理过期的nonce
+	if len(s.nonces) >= 1024 {
+		for k, expireAt := range s.nonces {
+			if !expireAt.After(now) {
+				delete(s.nonces, k)
+			}
+		}
+	}
+	s.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// redisNonceStore 基于Redis的nonce记录，适用于集群部署
+type redisNonceStore struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+// NewRedisNonceStore 创建基于Redis的nonce记录
+func NewRedisNonceStore(rdb redis.UniversalClient, prefix string) NonceStore {
+	return &redisNonceStore{rdb: rdb, prefix: prefix}
+}
+
+func (s *redisNonceStore) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.rdb.SetNX(ctx, s.prefix+":nonce:"+nonce, 1, ttl).Result()
+}
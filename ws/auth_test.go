@@ -0,0 +1,60 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/succko/hera/pb"
+)
+
+func TestJwtAuthenticatorTenant(t *testing.T) {
+	const secret = "secret"
+	a := NewJwtAuthenticator(StaticSecrets(secret, nil), AuthOptions{})
+	sign := func(tenant interface{}) string {
+		claims := jwt.MapClaims{"sub": "u1", "exp": time.Now().Add(time.Minute).Unix()}
+		if tenant != nil {
+			claims["tenant_id"] = tenant
+		}
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	tests := []struct {
+		name     string
+		claim    interface{}
+		tenantId int64
+		ok       bool
+	}{
+		{"absent", nil, 7, true},
+		{"number", 7, 7, true},
+		{"large number", 1000000, 1000000, true},
+		{"larger number", int64(123456789012), 123456789012, true},
+		{"string", "1000000", 1000000, true},
+		{"mismatch", 1000000, 1000001, false},
+		{"fraction", 7.5, 7, false},
+		{"bad string", "7a", 7, false},
+		{"bool", true, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.Authenticate(context.Background(), &pb.HeartBeatPacket{Id: "u1", TenantId: tt.tenantId, Sign: sign(tt.claim)})
+			if (err == nil) != tt.ok {
+				t.Fatalf("Authenticate() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTenantMatchesJsonNumber(t *testing.T) {
+	if !tenantMatches(json.Number("1000000"), 1000000) {
+		t.Fatal("json.Number 1000000 should match")
+	}
+	if tenantMatches(json.Number("1e6"), 1000000) {
+		t.Fatal("json.Number 1e6 is not an integer literal")
+	}
+}
@@ -6,19 +6,16 @@ package ws
 
 import (
 	"bytes"
+	"context"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/websocket"
-	"github.com/nacos-group/nacos-sdk-go/v2/util"
 	"github.com/succko/hera/mq"
 	"github.com/succko/hera/pb"
-	"github.com/succko/hera/utils"
 	"go.uber.org/zap"
-	"math"
 	"net/http"
-	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,12 +31,6 @@ const (
 
 	// maxMessageSize 定义了允许从对等端接收的最大消息大小。
 	maxMessageSize = 512
-
-	// secretKey 定义了用于计算签名的密钥。
-	secretKey = "%YhM=ULje*eX"
-
-	// timeout 定义了客户端超时时间。
-	timeout = float64(60 * time.Second * 3600)
 )
 
 var (
@@ -58,11 +49,14 @@ var upgrader = websocket.Upgrader{
 
 // Client 是websocket连接和hub之间的中间件。
 type Client struct {
-	hub  *Hub            // hub是该Client所属的Hub实例。
-	conn *websocket.Conn // conn是与websocket连接相关的网络连接。
-	send chan []byte     // send是用于向hub发送消息的缓冲通道。
-	uuid string          // uuid是客户端的唯一标识符。
-	hbts int             // 最后一次心跳时间
+	hub      *Hub            // hub是该Client所属的Hub实例。
+	conn     *websocket.Conn // conn是与websocket连接相关的网络连接。
+	send     chan []byte     // send是用于向hub发送消息的缓冲通道。
+	uuid     string          // uuid是客户端的唯一标识符，首次认证时写入一次。
+	hbts     int             // 最后一次心跳时间
+	closed   bool            // send通道是否已关闭，由hub.idsMu保护
+	tenantId int64           // 租户ID，首次认证时写入一次
+	authed   atomic.Bool     // 是否已认证
 }
 
 // readPump 将websocket连接上的消息泵送到hub。
@@ -70,7 +64,10 @@ type Client struct {
 // 应用程序在每个连接上运行readPump goroutine。通过在此goroutine中执行所有读取操作，确保连接上最多只有一个读取器。
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.quit:
+		}
 		err := c.conn.Close()
 		if err != nil {
 			zap.L().Error("readPump close error", zap.Error(err))
@@ -111,6 +108,7 @@ func (c *Client) readPump() {
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
+		c.hub.pumps.Done()
 		ticker.Stop()
 		err := c.conn.Close()
 		if err != nil {
@@ -128,7 +126,7 @@ func (c *Client) writePump() {
 			}
 			if !ok {
 				// hub关闭了通道。
-				err := c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				err := c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
 				if err != nil {
 					zap.L().Error("writePump conn.WriteMessage error", zap.Error(err))
 					return
@@ -182,85 +180,148 @@ func (c *Client) writePump() {
 
 // ServeWs 处理来自对等端的websocket请求。
 func ServeWs(w http.ResponseWriter, r *http.Request) {
+	h := SingletonHub()
+	// hub开始关闭后拒绝新的连接，避免 pumps.Add 与 Shutdown 中的 pumps.Wait 并发
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	h.pumps.Add(1)
+	h.mu.Unlock()
 	// 将HTTP响应写入和请求作为参数传递给WebSocket升级函数
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		h.pumps.Done()
 		zap.L().Error("ServeWs upgrader.Upgrade err", zap.Error(err))
 		return
 	}
 	// 创建一个新的客户端实例并将其连接到WebSocket Hub
-	c := &Client{hub: SingletonHub(), conn: conn, send: make(chan []byte, 256)}
-	c.hub.register <- c
+	c := &Client{hub: h, conn: conn, send: make(chan []byte, 256)}
+	select {
+	case c.hub.register <- c:
+	case <-c.hub.quit:
+		// hub已关闭，拒绝新的连接
+		c.hub.pumps.Done()
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+		_ = conn.Close()
+		return
+	}
+
+	// 宽限期内未完成认证的连接将被关闭
+	time.AfterFunc(c.hub.authGrace, func() {
+		if !c.authed.Load() {
+			zap.L().Info("ServeWs auth grace period expired, closing connection")
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "unauthenticated"), time.Now().Add(writeWait))
+			_ = conn.Close()
+		}
+	})
 
 	// 使用新的goroutine执行读取和写入循环，允许调用者引用与客户端相关联的内存
 	go c.writePump()
 	go c.readPump()
 }
 
-// 发送消息给客户端
-func (c *Client) sendMessage(message []byte) {
-	c.send <- message
-	zap.L().Info("sendMessage message:"+string(message), zap.String("uuid", c.uuid))
+// 客户端在hub中的标识，由租户和uuid组成
+func (c *Client) key() string {
+	return scopedKey(c.tenantId, c.uuid)
 }
 
-// SendMessage 发送消息给客户端
-func sendMessage(uuid string, innoPacket *pb.InnoPacket) {
-	clients, ok := SingletonHub().ids[uuid]
-	if ok {
-		zap.L().Info("sendMessage uuid not online", zap.String("uuid", uuid))
+// 发送消息给客户端
+func (c *Client) sendMessage(message []byte) {
+	// 与 unregister 互斥，已注销的客户端send通道已关闭
+	c.hub.idsMu.RLock()
+	defer c.hub.idsMu.RUnlock()
+	if c.closed {
 		return
 	}
-	message, _ := proto.Marshal(innoPacket)
-	for _, c := range clients {
-		c.sendMessage(message)
+	select {
+	case c.send <- message:
+		zap.L().Info("sendMessage message:"+string(message), zap.String("uuid", c.uuid))
+	default:
+		zap.L().Error("sendMessage send buffer full, message dropped", zap.String("uuid", c.uuid))
 	}
 }
 
-func checkSign(heartBeat *pb.HeartBeatPacket) bool {
-	if math.Abs(float64(heartBeat.GetTs()-time.Now().Unix())) > timeout {
-		zap.L().Error("checkSign heartBeat timeout")
+// 在客户端未注销且缓冲未满时发送消息，返回是否发送成功
+func (c *Client) trySend(message []byte) bool {
+	c.hub.idsMu.RLock()
+	defer c.hub.idsMu.RUnlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- message:
+		return true
+	default:
 		return false
 	}
-	sign := util.Md5(heartBeat.GetId() + strconv.Itoa(int(heartBeat.GetTs())) + secretKey)
-	return sign == heartBeat.GetSign()
+}
+
+// SendMessage 发送消息给key对应的客户端，集群模式下同时转发给其所在的其他节点，返回是否送达
+func sendMessage(key string, innoPacket *pb.InnoPacket) bool {
+	h := SingletonHub()
+	message, _ := proto.Marshal(innoPacket)
+	delivered := h.deliverLocal(key, message)
+	if h.cluster != nil && h.cluster.forward(key, message) {
+		delivered = true
+	}
+	if !delivered {
+		zap.L().Info("sendMessage uuid not online", zap.String("key", key))
+	}
+	return delivered
 }
 
 func (c *Client) auth(innoPacket *pb.InnoPacket) {
-	var mu sync.Mutex
-	if !checkSign(innoPacket.GetHeartBeat()) {
-		zap.L().Error("handleC2S heartBeat sign error, message:"+innoPacket.GetHeartBeat().String(), zap.String("uuid", c.uuid))
+	if c.hub.authenticator == nil {
+		zap.L().Error("handleC2S heartBeat no authenticator configured", zap.String("uuid", c.uuid))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	// 已认证的连接重复发送心跳时不做重放检查，uuid 与租户仍需与首次认证一致
+	if c.authed.Load() {
+		ctx = context.WithValue(ctx, reauthKey{}, true)
+	}
+	err := c.hub.authenticator.Authenticate(ctx, innoPacket.GetHeartBeat())
+	cancel()
+	if err != nil {
+		zap.L().Error("handleC2S heartBeat auth error, message:"+innoPacket.GetHeartBeat().String(), zap.String("uuid", c.uuid), zap.Error(err))
 		return
 	}
-	// 更新客户端uuid
-	if c.uuid != "" && c.uuid != innoPacket.GetHeartBeat().GetId() {
+	first := !c.authed.Load()
+	// 同一连接不允许切换uuid与租户
+	if !first && c.uuid != innoPacket.GetHeartBeat().GetId() {
 		zap.L().Error("handleC2S heartBeat uuid error, message:"+innoPacket.GetHeartBeat().String(), zap.String("uuid", c.uuid))
 		return
 	}
-	c.uuid = innoPacket.GetHeartBeat().GetId()
+	if !first && c.tenantId != innoPacket.GetHeartBeat().GetTenantId() {
+		zap.L().Error("handleC2S heartBeat tenant error, message:"+innoPacket.GetHeartBeat().String(), zap.String("uuid", c.uuid))
+		return
+	}
+	// uuid 与租户只在首次认证时写入，持有 idsMu 以与 hub 中持锁的读取互斥，之后只读
+	if first {
+		c.hub.idsMu.Lock()
+		c.uuid = innoPacket.GetHeartBeat().GetId()
+		c.tenantId = innoPacket.GetHeartBeat().GetTenantId()
+		c.hub.idsMu.Unlock()
+	}
 	c.hbts = int(innoPacket.GetHeartBeat().GetTs())
+	c.authed.Store(true)
 
 	// 判断是否已经存在该客户端
-	func() {
-		mu.Lock()
-		defer mu.Unlock()
-		if c.hub.ids == nil {
-			c.hub.ids = make(map[string][]*Client)
-		}
-		flag := false
-		for _, v := range c.hub.ids[c.uuid] {
-			if v == c {
-				flag = true
-				break
-			}
-		}
-		if !flag {
-			c.hub.ids[c.uuid] = append(c.hub.ids[c.uuid], c)
-		}
-	}()
+	c.hub.bind(c)
+	// 刷新集群在线记录
+	if c.hub.cluster != nil {
+		c.hub.cluster.refresh(c.key())
+	}
+
+	zap.L().Info("hub client heartBeat auth", zap.String("uuid", c.uuid), zap.Int64("tenantId", c.tenantId), zap.String("all", strings.Join(c.hub.uuids(), ",")))
 
-	//c.hub.boys[c.uuid] = c
-	//c.hub.girls[c.uuid] = c
-	zap.L().Info("hub client heartBeat auth", zap.Int("clients", len(c.hub.clients)), zap.String("uuid", c.uuid), zap.Int("ids", len(c.hub.ids[c.uuid])), zap.String("all", strings.Join(utils.MapKeys(c.hub.ids), ",")))
+	// 首次认证成功后补发离线消息
+	if first {
+		go c.replayOffline()
+	}
 }
 
 func (c *Client) handleC2S(message []byte) {
@@ -281,12 +342,39 @@ func (c *Client) handleC2S(message []byte) {
 	zap.L().Info("handleC2S innoPacket:"+innoPacket.String(), zap.String("uuid", c.uuid))
 	if innoPacket.Type == pb.InnoPacket_TYPE_HEARTBEAT {
 		c.auth(innoPacket)
+	} else if innoPacket.Type == pb.InnoPacket_TYPE_ROOM {
+		if !c.authed.Load() {
+			zap.L().Error("handleC2S room from unauthenticated client, message:" + innoPacket.String())
+			return
+		}
+		c.handleRoom(innoPacket.GetRoom())
 	} else if innoPacket.Type == pb.InnoPacket_TYPE_INSTRUCTION {
-		if innoPacket.GetInstruction().GetToId() != "" {
-			sendMessage(innoPacket.GetInstruction().GetToId(), innoPacket)
+		// 未认证的客户端不允许发送指令
+		if !c.authed.Load() {
+			zap.L().Error("handleC2S instruction from unauthenticated client, message:" + innoPacket.String())
+			return
+		}
+		instruction := innoPacket.GetInstruction()
+		if instruction == nil {
+			zap.L().Error("handleC2S instruction missing, message:"+innoPacket.String(), zap.String("uuid", c.uuid))
+			return
+		}
+		// 指令只能发给发送方所在租户
+		instruction.TenantId = c.tenantId
+		// 接收方的确认由hub处理，不再转发
+		if c.hub.acker != nil && instruction.GetReport() == pb.InstructionPacket_STATUS_ACK {
+			c.hub.ackReceived(c.key(), instruction.GetRequestId())
+			return
+		}
+		if instruction.GetRoomId() != "" {
+			c.sendToRoom(innoPacket)
+		} else if instruction.GetToId() != "" {
+			deliver(innoPacket.GetInstruction().GetToId(), innoPacket, false)
 		} else {
 			// 生产MQ消息
-			mq.Producer.SendSync("instruct_c2s", innoPacket)
+			if err := mq.Publish(context.Background(), "instruct_c2s", innoPacket); err != nil {
+				zap.L().Error("handleC2S publish error, message:"+innoPacket.String(), zap.String("uuid", c.uuid), zap.Error(err))
+			}
 		}
 	}
 }
@@ -303,18 +391,28 @@ func InstructS2cHandle(message []byte) {
 	HandleS2C(innoPacket)
 }
 
-func HandleS2C(innoPacket *pb.InnoPacket) {
+// HandleS2C 处理服务端下发的指令，返回投递结果
+func HandleS2C(innoPacket *pb.InnoPacket) Delivery {
 	// 根据不同的指令，执行不同的操作
 	zap.L().Info("HandleS2C innoPacket:" + innoPacket.String())
 	if innoPacket.Type == pb.InnoPacket_TYPE_HEARTBEAT {
-		return
+		return DeliveryNone
 	} else if innoPacket.Type == pb.InnoPacket_TYPE_INSTRUCTION {
+		if roomId := innoPacket.GetInstruction().GetRoomId(); roomId != "" {
+			if _, err := SingletonHub().SendToRoom(innoPacket.GetInstruction().GetTenantId(), roomId, innoPacket); err != nil {
+				zap.L().Error("HandleS2C sendToRoom error", zap.String("roomId", roomId), zap.Error(err))
+				return DeliveryNone
+			}
+			return DeliveryRoom
+		}
 		if innoPacket.GetInstruction().GetToId() != "" {
 			if strings.ToUpper(innoPacket.GetInstruction().GetToId()) == "ALL" {
 				SingletonHub().Broadcast(innoPacket)
+				return DeliveryBroadcast
 			} else {
-				sendMessage(innoPacket.GetInstruction().GetToId(), innoPacket)
+				return deliver(innoPacket.GetInstruction().GetToId(), innoPacket, true)
 			}
 		}
 	}
+	return DeliveryNone
 }
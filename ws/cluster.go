@@ -0,0 +1,212 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// 集群消息类型
+const (
+	kindDirect    = "direct"
+	kindBroadcast = "broadcast"
+	kindAck       = "ack"
+)
+
+// 单次Redis操作的超时时间
+const clusterOpTimeout = 3 * time.Second
+
+// envelope 节点之间传递的消息
+type envelope struct {
+	Kind    string `json:"kind"`
+	Origin  string `json:"origin"`           // 发送节点
+	To      string `json:"to,omitempty"`     // 定向消息的目标key
+	Tenant  int64  `json:"tenant,omitempty"` // 广播消息的租户
+	Message []byte `json:"message"`          // 序列化后的InnoPacket，确认消息为requestId
+}
+
+// cluster 基于Redis发布订阅在多个节点之间投递消息，并维护uuid到节点的在线记录。
+//
+// 每个节点订阅广播频道和自己的节点频道；定向消息根据在线记录只发布到目标uuid所在节点的频道。
+// 在线记录是一个以过期时间为分值的有序集合，由客户端心跳刷新。
+type cluster struct {
+	hub    *Hub
+	rdb    redis.UniversalClient
+	node   string
+	prefix string
+	ttl    time.Duration
+	pubsub *redis.PubSub
+}
+
+// EnableCluster 开启集群模式，需在 Run 之前调用。
+func (h *Hub) EnableCluster(rdb redis.UniversalClient, prefix string, ttl time.Duration) error {
+	c := &cluster{
+		hub:    h,
+		rdb:    rdb,
+		node:   nodeId(),
+		prefix: prefix,
+		ttl:    ttl,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	c.pubsub = rdb.Subscribe(ctx, c.broadcastChannel(), c.nodeChannel(c.node))
+	// 等待订阅确认，确保启用后不会丢失消息
+	if _, err := c.pubsub.Receive(ctx); err != nil {
+		_ = c.pubsub.Close()
+		return err
+	}
+	h.cluster = c
+	// 房间成员在各节点之间共享
+	h.rooms = &redisRoomStore{rdb: rdb, prefix: prefix}
+	go c.receive()
+	zap.L().Info("ws hub cluster enabled", zap.String("node", c.node), zap.String("prefix", prefix))
+	return nil
+}
+
+// Node 当前节点标识，未开启集群模式时返回空字符串。
+func (h *Hub) Node() string {
+	if h.cluster == nil {
+		return ""
+	}
+	return h.cluster.node
+}
+
+// 生成节点标识：主机名加随机后缀，避免同一主机上的多个进程冲突
+func nodeId() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return host + "-" + strconv.Itoa(os.Getpid()) + "-" + hex.EncodeToString(b)
+}
+
+func (c *cluster) broadcastChannel() string {
+	return c.prefix + ":broadcast"
+}
+
+func (c *cluster) nodeChannel(node string) string {
+	return c.prefix + ":node:" + node
+}
+
+func (c *cluster) presenceKey(uuid string) string {
+	return c.prefix + ":presence:" + uuid
+}
+
+// 刷新uuid在当前节点的在线记录
+func (c *cluster) refresh(uuid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	key := c.presenceKey(uuid)
+	expireAt := time.Now().Add(c.ttl).Unix()
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(expireAt), Member: c.node})
+		pipe.Expire(ctx, key, c.ttl)
+		return nil
+	})
+	if err != nil {
+		zap.L().Error("ws cluster refresh presence error", zap.String("uuid", uuid), zap.Error(err))
+	}
+}
+
+// 移除uuid在当前节点的在线记录
+func (c *cluster) leave(uuid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := c.rdb.ZRem(ctx, c.presenceKey(uuid), c.node).Err(); err != nil {
+		zap.L().Error("ws cluster leave presence error", zap.String("uuid", uuid), zap.Error(err))
+	}
+}
+
+// Nodes 查询uuid当前在线的所有节点
+func (c *cluster) nodes(uuid string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	key := c.presenceKey(uuid)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	// 清理已过期的节点
+	c.rdb.ZRemRangeByScore(ctx, key, "-inf", "("+now)
+	nodes, err := c.rdb.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		zap.L().Error("ws cluster query presence error", zap.String("uuid", uuid), zap.Error(err))
+		return nil
+	}
+	return nodes
+}
+
+// 转发定向消息给uuid所在的其他节点，返回是否存在这样的节点
+func (c *cluster) forward(uuid string, message []byte) bool {
+	forwarded := false
+	for _, node := range c.nodes(uuid) {
+		if node == c.node {
+			continue
+		}
+		if c.publish(c.nodeChannel(node), &envelope{Kind: kindDirect, Origin: c.node, To: uuid, Message: message}) {
+			forwarded = true
+		}
+	}
+	return forwarded
+}
+
+// 广播消息给其他节点中指定租户的客户端
+func (c *cluster) broadcast(tenantId int64, message []byte) {
+	c.publish(c.broadcastChannel(), &envelope{Kind: kindBroadcast, Origin: c.node, Tenant: tenantId, Message: message})
+}
+
+// 转发接收方的确认给其他节点
+func (c *cluster) forwardAck(toId, requestId string) {
+	c.publish(c.broadcastChannel(), &envelope{Kind: kindAck, Origin: c.node, To: toId, Message: []byte(requestId)})
+}
+
+func (c *cluster) publish(channel string, e *envelope) bool {
+	data, _ := json.Marshal(e)
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := c.rdb.Publish(ctx, channel, data).Err(); err != nil {
+		zap.L().Error("ws cluster publish error", zap.String("channel", channel), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// 接收其他节点发来的消息，并投递给本节点的客户端
+func (c *cluster) receive() {
+	for msg := range c.pubsub.Channel() {
+		var e envelope
+		if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+			zap.L().Error("ws cluster receive error", zap.String("channel", msg.Channel), zap.Error(err))
+			continue
+		}
+		if e.Origin == c.node {
+			continue
+		}
+		switch e.Kind {
+		case kindDirect:
+			// 在线记录已失效，移除当前节点
+			if !c.hub.deliverLocal(e.To, e.Message) {
+				c.leave(e.To)
+			}
+		case kindBroadcast:
+			c.hub.broadcastLocal(e.Tenant, e.Message)
+		case kindAck:
+			if c.hub.acker != nil {
+				c.hub.acker.ack(e.To, string(e.Message))
+			}
+		}
+	}
+}
+
+// 关闭集群连接并移除当前节点的所有在线记录
+func (c *cluster) close() {
+	for _, uuid := range c.hub.uuids() {
+		c.leave(uuid)
+	}
+	if err := c.pubsub.Close(); err != nil {
+		zap.L().Error("ws cluster close error", zap.Error(err))
+	}
+}
@@ -0,0 +1,143 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/succko/hera/mq"
+	"github.com/succko/hera/pb"
+	"go.uber.org/zap"
+)
+
+// Delivery 指令的投递结果
+type Delivery int
+
+const (
+	DeliveryNone      Delivery = iota // 无需投递，例如心跳包或没有目标
+	DeliveryOnline                    // 已投递给在线客户端
+	DeliveryBroadcast                 // 已广播
+	DeliveryStored                    // 目标不在线，已保存为离线消息
+	DeliveryOffline                   // 目标不在线，消息被丢弃
+	DeliveryRoom                      // 已投递给房间成员
+)
+
+func (d Delivery) String() string {
+	switch d {
+	case DeliveryOnline:
+		return "online"
+	case DeliveryBroadcast:
+		return "broadcast"
+	case DeliveryStored:
+		return "stored"
+	case DeliveryOffline:
+		return "offline"
+	case DeliveryRoom:
+		return "room"
+	default:
+		return "none"
+	}
+}
+
+// 投递指令给指令所属租户中的toId，目标不在线时按offlineSend保存离线消息，并向发送方报告STATUS_OFFLINE。
+// backend 表示发送方为服务端，其状态报告发布到MQ。
+func deliver(toId string, innoPacket *pb.InnoPacket, backend bool) Delivery {
+	h := SingletonHub()
+	key := scopedKey(innoPacket.GetInstruction().GetTenantId(), toId)
+	if sendMessage(key, innoPacket) {
+		if h.acker != nil {
+			h.acker.track(key, innoPacket, backend)
+		}
+		return DeliveryOnline
+	}
+	result := DeliveryOffline
+	if h.saveOffline(key, innoPacket) {
+		result = DeliveryStored
+	}
+	reportStatus(innoPacket, pb.InstructionPacket_STATUS_OFFLINE, backend)
+	return result
+}
+
+// SetReportTopic 设置服务端发送的指令的状态报告MQ主题，为空时仅记录日志。
+func (h *Hub) SetReportTopic(topic string) {
+	h.reportTopic = topic
+}
+
+// SubscribeReports 订阅租户内服务端指令的状态报告，buffer为缓冲大小，缓冲满时丢弃报告。
+// 仅能收到由本节点投递的指令的报告，返回的cancel用于取消订阅。
+func (h *Hub) SubscribeReports(tenantId int64, buffer int) (<-chan *pb.InnoPacket, func()) {
+	ch := make(chan *pb.InnoPacket, buffer)
+	h.reportsMu.Lock()
+	h.reports[ch] = tenantId
+	h.reportsMu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.reportsMu.Lock()
+			delete(h.reports, ch)
+			h.reportsMu.Unlock()
+		})
+	}
+}
+
+// 将状态报告发送给订阅者
+func (h *Hub) publishReport(report *pb.InnoPacket) {
+	tenantId := report.GetInstruction().GetTenantId()
+	h.reportsMu.RLock()
+	defer h.reportsMu.RUnlock()
+	for ch, t := range h.reports {
+		if t != tenantId {
+			continue
+		}
+		select {
+		case ch <- report:
+		default:
+			zap.L().Error("publishReport subscriber buffer full, report dropped", zap.String("requestId", report.GetInstruction().GetRequestId()))
+		}
+	}
+}
+
+// 向指令的发送方报告投递状态，客户端发送方通过websocket报告，服务端发送方通过MQ报告
+func reportStatus(innoPacket *pb.InnoPacket, status pb.InstructionPacket_ReportStatus, backend bool) {
+	report := statusPacket(innoPacket, status)
+	if backend {
+		SingletonHub().publishReport(report)
+		topic := SingletonHub().reportTopic
+		if topic == "" {
+			zap.L().Info("reportStatus "+report.String(), zap.String("status", status.String()))
+			return
+		}
+		if err := mq.Publish(context.Background(), topic, report); err != nil {
+			zap.L().Error("reportStatus publish error "+report.String(), zap.String("topic", topic), zap.Error(err))
+		}
+		return
+	}
+	fromId := innoPacket.GetInstruction().GetFromId()
+	if fromId == "" {
+		return
+	}
+	if !sendMessage(scopedKey(innoPacket.GetInstruction().GetTenantId(), fromId), report) {
+		zap.L().Info("reportStatus sender not online", zap.String("uuid", fromId), zap.String("status", status.String()))
+	}
+}
+
+// 根据原始指令构造状态报告，报告由原目标发回原发送方
+func statusPacket(innoPacket *pb.InnoPacket, status pb.InstructionPacket_ReportStatus) *pb.InnoPacket {
+	instruction := innoPacket.GetInstruction()
+	return &pb.InnoPacket{
+		Type: pb.InnoPacket_TYPE_INSTRUCTION,
+		Data: &pb.InnoPacket_Instruction{
+			Instruction: &pb.InstructionPacket{
+				RequestId: instruction.GetRequestId(),
+				Pid:       instruction.GetPid(),
+				FromId:    instruction.GetToId(),
+				ToId:      instruction.GetFromId(),
+				TimeStamp: time.Now().UnixMilli(),
+				Code:      instruction.GetCode(),
+				Report:    status,
+				TenantId:  instruction.GetTenantId(),
+				RoomId:    instruction.GetRoomId(),
+			},
+		},
+	}
+}
@@ -1,12 +1,14 @@
 package ws
 
 import (
+	"context"
 	"github.com/golang/protobuf/proto"
 	"github.com/succko/hera/pb"
 	"github.com/succko/hera/utils"
 	"go.uber.org/zap"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Hub Hub维护活跃客户端的集合，并向客户端广播消息。
@@ -17,14 +19,11 @@ type Hub struct {
 	// 客户端的ID集合。
 	ids map[string][]*Client
 
-	// 男生的客户端集合。
-	boys map[string]*Client
+	// 保护客户端的ID集合及客户端send通道的关闭。
+	idsMu sync.RWMutex
 
-	// 女生的客户端集合。
-	girls map[string]*Client
-
-	// 客户端发送的入站消息。
-	broadcast chan []byte
+	// 待广播的消息，仅投递给同一租户的客户端。
+	broadcast chan tenantMessage
 
 	// 客户端的注册请求。
 	register chan *Client
@@ -34,6 +33,54 @@ type Hub struct {
 
 	// 运行状态。
 	running bool
+
+	// 已开始关闭，拒绝新的连接。
+	closing bool
+
+	// 保护运行与关闭状态。
+	mu sync.Mutex
+
+	// 关闭信号。
+	quit chan struct{}
+
+	// 关闭信号只发送一次。
+	quitOnce sync.Once
+
+	// 活跃的写入泵，关闭时等待其发送关闭帧。
+	pumps sync.WaitGroup
+
+	// 集群模式，为nil时仅在本节点内投递。
+	cluster *cluster
+
+	// 离线消息存储，为nil时不保存离线消息。
+	offline OfflineStore
+
+	// 指令确认与重试，为nil时不跟踪确认。
+	acker *acker
+
+	// 服务端指令的状态报告MQ主题。
+	reportTopic string
+
+	// 服务端指令的状态报告订阅者，值为订阅的租户。
+	reports map[chan *pb.InnoPacket]int64
+
+	// 保护状态报告订阅者。
+	reportsMu sync.RWMutex
+
+	// 房间成员存储。
+	rooms roomStore
+
+	// 客户端认证方式。
+	authenticator Authenticator
+
+	// 未认证连接的宽限期。
+	authGrace time.Duration
+}
+
+// tenantMessage 指定租户的广播消息
+type tenantMessage struct {
+	tenantId int64
+	message  []byte
 }
 
 var (
@@ -47,12 +94,14 @@ func SingletonHub() *Hub {
 		hub = &Hub{
 			clients:    make(map[*Client]bool),
 			ids:        make(map[string][]*Client),
-			boys:       make(map[string]*Client),
-			girls:      make(map[string]*Client),
-			broadcast:  make(chan []byte),
+			broadcast:  make(chan tenantMessage),
 			register:   make(chan *Client),
 			unregister: make(chan *Client),
 			running:    false,
+			quit:       make(chan struct{}),
+			rooms:      newMemoryRoomStore(),
+			reports:    make(map[chan *pb.InnoPacket]int64),
+			authGrace:  defaultAuthGrace,
 		}
 		zap.L().Info("ws hub init")
 	})
@@ -61,18 +110,16 @@ func SingletonHub() *Hub {
 
 // Run 运行Hub。
 func (h *Hub) Run() {
-	var mu sync.Mutex
 	// 如果hub已经正在运行，则直接返回
-	mu.Lock()
-	defer mu.Unlock()
-	if hub.running {
+	h.mu.Lock()
+	if h.running {
+		h.mu.Unlock()
 		zap.L().Info("ws hub run already")
-		mu.Unlock()
 		return
 	}
 	zap.L().Info("ws hub run start")
-	hub.running = true // 将hub的running标志设置为true，表示正在运行
-	mu.Unlock()
+	h.running = true // 将hub的running标志设置为true，表示正在运行
+	h.mu.Unlock()
 	for {
 		select {
 		case client := <-h.register: // 从h.register通道接收新连接的客户端
@@ -81,14 +128,120 @@ func (h *Hub) Run() {
 			unregister(client)
 		case message := <-h.broadcast: // 从h.broadcast通道接收广播消息
 			broadcast(message)
+		case <-h.quit: // 关闭所有客户端后退出
+			if h.cluster != nil {
+				h.cluster.close()
+			}
+			for c := range h.clients {
+				unregister(c)
+			}
+			h.mu.Lock()
+			h.running = false
+			h.mu.Unlock()
+			zap.L().Info("ws hub run stop")
+			return
 		}
 	}
 }
 
-// Broadcast 广播消息给所有客户端
+// Shutdown 关闭Hub，向所有客户端发送关闭帧，并等待发送完成或ctx超时。
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	h.mu.Unlock()
+	h.quitOnce.Do(func() {
+		close(h.quit)
+	})
+	done := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Broadcast 广播消息给指令所属租户的所有客户端，集群模式下同时广播给其他节点
 func (h *Hub) Broadcast(innoPacket *pb.InnoPacket) {
+	tenantId := innoPacket.GetInstruction().GetTenantId()
 	message, _ := proto.Marshal(innoPacket)
-	h.broadcast <- message
+	h.broadcastLocal(tenantId, message)
+	if h.cluster != nil {
+		h.cluster.broadcast(tenantId, message)
+	}
+}
+
+// 广播消息给本节点中指定租户的所有客户端
+func (h *Hub) broadcastLocal(tenantId int64, message []byte) {
+	select {
+	case h.broadcast <- tenantMessage{tenantId: tenantId, message: message}:
+	case <-h.quit:
+	}
+}
+
+// 投递消息给本节点中指定key的客户端，返回是否存在该客户端
+func (h *Hub) deliverLocal(key string, message []byte) bool {
+	// 复制后释放锁再发送，sendMessage 会重新持锁检查客户端是否已注销
+	h.idsMu.RLock()
+	clients := append([]*Client(nil), h.ids[key]...)
+	h.idsMu.RUnlock()
+	for _, c := range clients {
+		c.sendMessage(message)
+	}
+	return len(clients) > 0
+}
+
+// 将已认证的客户端加入ID集合
+func (h *Hub) bind(c *Client) {
+	h.idsMu.Lock()
+	defer h.idsMu.Unlock()
+	// 已注销的客户端send通道已关闭，不再加入
+	if c.closed {
+		return
+	}
+	key := c.key()
+	for _, v := range h.ids[key] {
+		if v == c {
+			return
+		}
+	}
+	h.ids[key] = append(h.ids[key], c)
+}
+
+// 将客户端从ID集合中移除，返回该uuid在本节点是否还有其他客户端
+func (h *Hub) unbind(c *Client) bool {
+	key := c.key()
+	clients := h.ids[key]
+	for i, v := range clients {
+		if v == c {
+			clients = append(clients[:i], clients[i+1:]...)
+			break
+		}
+	}
+	if len(clients) == 0 {
+		delete(h.ids, key)
+		return false
+	}
+	h.ids[key] = clients
+	return true
+}
+
+// Online 本节点是否存在指定租户中uuid的客户端
+func (h *Hub) Online(tenantId int64, uuid string) bool {
+	h.idsMu.RLock()
+	defer h.idsMu.RUnlock()
+	return len(h.ids[scopedKey(tenantId, uuid)]) > 0
+}
+
+// 本节点所有已认证客户端的key
+func (h *Hub) uuids() []string {
+	h.idsMu.RLock()
+	defer h.idsMu.RUnlock()
+	return utils.MapKeys(h.ids)
 }
 
 // 注册客户端。
@@ -102,16 +255,30 @@ func unregister(c *Client) {
 	if _, ok := hub.clients[c]; ok { // 判断该客户端是否存在于h.clients映射中
 		writeLog("unregister", c)
 		delete(hub.clients, c) // 从h.clients映射中删除该客户端
-		close(c.send)          // 关闭该客户端的send通道
+		// 持锁读取认证状态，与 bind 互斥：之前已加入ID集合的客户端在此移除，之后的 bind 见到 closed 不再加入
+		hub.idsMu.Lock()
+		authed := c.authed.Load()
+		online := authed && hub.unbind(c)
+		c.closed = true
+		close(c.send) // 关闭该客户端的send通道
+		hub.idsMu.Unlock()
+		// 该uuid已不在本节点，移除在线记录
+		if authed && !online && hub.cluster != nil {
+			hub.cluster.leave(c.key())
+		}
 	}
 }
 
-// 广播消息给所有客户端。
-func broadcast(message []byte) {
+// 广播消息给租户内所有已认证的客户端。
+func broadcast(m tenantMessage) {
 	writeLog("broadcast", nil)
 	for c := range hub.clients { // 遍历h.clients映射中的所有客户端
+		// 未认证或其他租户的客户端不接收广播
+		if !c.authed.Load() || c.tenantId != m.tenantId {
+			continue
+		}
 		select {
-		case c.send <- message: // 将广播消息发送给该客户端
+		case c.send <- m.message: // 将广播消息发送给该客户端
 		default:
 			unregister(c)
 		}
@@ -119,21 +286,20 @@ func broadcast(message []byte) {
 }
 
 func writeLog(msg string, c *Client) {
+	hub.idsMu.RLock()
+	defer hub.idsMu.RUnlock()
 	if c == nil {
 		zap.L().Info("hub client "+msg,
 			zap.Int("clients", len(hub.clients)),
 			zap.String("all", strings.Join(utils.MapKeys(hub.ids), ",")),
-			zap.String("boys", strings.Join(utils.MapKeys(hub.boys), ",")),
-			zap.String("girls", strings.Join(utils.MapKeys(hub.girls), ",")),
 		)
 	} else {
 		zap.L().Info("hub client "+msg,
 			zap.Int("clients", len(hub.clients)),
 			zap.String("uuid", c.uuid),
-			zap.Int("ids", len(hub.ids[c.uuid])),
+			zap.Int64("tenantId", c.tenantId),
+			zap.Int("ids", len(hub.ids[c.key()])),
 			zap.String("all", strings.Join(utils.MapKeys(hub.ids), ",")),
-			zap.String("boys", strings.Join(utils.MapKeys(hub.boys), ",")),
-			zap.String("girls", strings.Join(utils.MapKeys(hub.girls), ",")),
 		)
 	}
 }
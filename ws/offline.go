@@ -0,0 +1,177 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/succko/hera/pb"
+	"go.uber.org/zap"
+)
+
+// 离线消息默认配置
+const (
+	defaultOfflineCap = 100
+	defaultOfflineTtl = 7 * 24 * time.Hour
+)
+
+// 每批补发的最大消息数量
+const replayBatch = 32
+
+// OfflineStore 离线消息存储，按保存顺序取出。
+type OfflineStore interface {
+	// Save 保存发给uuid的消息，超出单用户上限时丢弃最早的消息。
+	Save(ctx context.Context, uuid string, message []byte) error
+	// Take 取出并删除uuid最早的最多limit条未过期消息，仅在没有未过期消息时返回空。
+	Take(ctx context.Context, uuid string, limit int) ([][]byte, error)
+	// Restore 将未能发送的消息按原顺序放回最前面，有效期重新计算。
+	Restore(ctx context.Context, uuid string, messages [][]byte) error
+}
+
+// OfflineOptions 离线消息存储的通用选项
+type OfflineOptions struct {
+	Cap int           // 单用户最多保存的消息数量，默认 100
+	Ttl time.Duration // 消息有效期，默认 7 天
+}
+
+func (o OfflineOptions) withDefaults() OfflineOptions {
+	if o.Cap <= 0 {
+		o.Cap = defaultOfflineCap
+	}
+	if o.Ttl <= 0 {
+		o.Ttl = defaultOfflineTtl
+	}
+	return o
+}
+
+// SetOfflineStore 设置离线消息存储，为nil时不保存离线消息，需在 Run 之前调用。
+func (h *Hub) SetOfflineStore(store OfflineStore) {
+	h.offline = store
+}
+
+// 保存离线消息，返回是否保存成功
+func (h *Hub) saveOffline(uuid string, innoPacket *pb.InnoPacket) bool {
+	if h.offline == nil || !innoPacket.GetOfflineSend() {
+		return false
+	}
+	message, _ := proto.Marshal(innoPacket)
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	if err := h.offline.Save(ctx, uuid, message); err != nil {
+		zap.L().Error("offline message save error", zap.String("uuid", uuid), zap.Error(err))
+		return false
+	}
+	zap.L().Info("offline message saved", zap.String("uuid", uuid))
+	return true
+}
+
+// 客户端认证成功后按顺序补发离线消息，每批不超过send通道的剩余容量
+func (c *Client) replayOffline() {
+	store := c.hub.offline
+	if store == nil {
+		return
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		room := cap(c.send) - len(c.send)
+		if room > replayBatch {
+			room = replayBatch
+		}
+		if room > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+			messages, err := store.Take(ctx, c.key(), room)
+			cancel()
+			if err != nil {
+				zap.L().Error("offline message take error", zap.String("uuid", c.uuid), zap.Error(err))
+				return
+			}
+			if len(messages) == 0 {
+				return
+			}
+			for i, message := range messages {
+				if !c.trySend(message) {
+					// 客户端已断开或缓冲已满，剩余消息放回最前面，下次补发时保持顺序
+					ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+					err := store.Restore(ctx, c.key(), messages[i:])
+					cancel()
+					if err != nil {
+						zap.L().Error("offline message restore error", zap.String("uuid", c.uuid), zap.Int("count", len(messages)-i), zap.Error(err))
+					}
+					return
+				}
+			}
+			zap.L().Info("offline message replayed", zap.String("uuid", c.uuid), zap.Int("count", len(messages)))
+			continue
+		}
+		<-ticker.C
+	}
+}
+
+// memoryOfflineStore 进程内的离线消息存储，用于测试及单节点部署
+type memoryOfflineStore struct {
+	mu       sync.Mutex
+	opts     OfflineOptions
+	messages map[string][]offlineEntry
+}
+
+type offlineEntry struct {
+	message  []byte
+	expireAt time.Time
+}
+
+// NewMemoryOfflineStore 创建进程内的离线消息存储
+func NewMemoryOfflineStore(opts OfflineOptions) OfflineStore {
+	return &memoryOfflineStore{
+		opts:     opts.withDefaults(),
+		messages: make(map[string][]offlineEntry),
+	}
+}
+
+func (s *memoryOfflineStore) Save(ctx context.Context, uuid string, message []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := append(s.messages[uuid], offlineEntry{message: message, expireAt: time.Now().Add(s.opts.Ttl)})
+	if len(entries) > s.opts.Cap {
+		entries = entries[len(entries)-s.opts.Cap:]
+	}
+	s.messages[uuid] = entries
+	return nil
+}
+
+func (s *memoryOfflineStore) Restore(ctx context.Context, uuid string, messages [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expireAt := time.Now().Add(s.opts.Ttl)
+	entries := make([]offlineEntry, 0, len(messages)+len(s.messages[uuid]))
+	for _, message := range messages {
+		entries = append(entries, offlineEntry{message: message, expireAt: expireAt})
+	}
+	entries = append(entries, s.messages[uuid]...)
+	if len(entries) > s.opts.Cap {
+		entries = entries[len(entries)-s.opts.Cap:]
+	}
+	s.messages[uuid] = entries
+	return nil
+}
+
+func (s *memoryOfflineStore) Take(ctx context.Context, uuid string, limit int) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	entries := s.messages[uuid]
+	var messages [][]byte
+	i := 0
+	for ; i < len(entries) && len(messages) < limit; i++ {
+		if entries[i].expireAt.After(now) {
+			messages = append(messages, entries[i].message)
+		}
+	}
+	if i == len(entries) {
+		delete(s.messages, uuid)
+	} else {
+		s.messages[uuid] = entries[i:]
+	}
+	return messages, nil
+}
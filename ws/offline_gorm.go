@@ -0,0 +1,111 @@
+package ws
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OfflineMessage 离线消息表
+type OfflineMessage struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	Uuid      string    `gorm:"size:64;not null;index"`
+	Message   []byte    `gorm:"not null"`
+	ExpireAt  time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+func (OfflineMessage) TableName() string {
+	return "ws_offline_messages"
+}
+
+// gormOfflineStore 基于数据库的离线消息存储
+type gormOfflineStore struct {
+	db   *gorm.DB
+	opts OfflineOptions
+}
+
+// NewGormOfflineStore 创建基于数据库的离线消息存储，并自动迁移离线消息表
+func NewGormOfflineStore(db *gorm.DB, opts OfflineOptions) (OfflineStore, error) {
+	if err := db.AutoMigrate(&OfflineMessage{}); err != nil {
+		return nil, err
+	}
+	return &gormOfflineStore{
+		db:   db,
+		opts: opts.withDefaults(),
+	}, nil
+}
+
+func (s *gormOfflineStore) Save(ctx context.Context, uuid string, message []byte) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&OfflineMessage{Uuid: uuid, Message: message, ExpireAt: time.Now().Add(s.opts.Ttl)}).Error; err != nil {
+			return err
+		}
+		// 超出上限时删除最早的消息
+		var ids []uint64
+		if err := tx.Model(&OfflineMessage{}).Where("uuid = ?", uuid).Order("id DESC").
+			Offset(s.opts.Cap).Limit(1).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			return tx.Where("uuid = ? AND id <= ?", uuid, ids[0]).Delete(&OfflineMessage{}).Error
+		}
+		return nil
+	})
+}
+
+func (s *gormOfflineStore) Take(ctx context.Context, uuid string, limit int) ([][]byte, error) {
+	var messages [][]byte
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 顺带清理已过期的消息
+		if err := tx.Where("uuid = ? AND expire_at <= ?", uuid, time.Now()).Delete(&OfflineMessage{}).Error; err != nil {
+			return err
+		}
+		var rows []OfflineMessage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", uuid).
+			Order("id ASC").Limit(limit).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		ids := make([]uint64, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+			messages = append(messages, row.Message)
+		}
+		return tx.Where("id IN ?", ids).Delete(&OfflineMessage{}).Error
+	})
+	return messages, err
+}
+
+// 消息按自增id排序，放回时连同已有消息按新顺序重新写入
+func (s *gormOfflineStore) Restore(ctx context.Context, uuid string, messages [][]byte) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []OfflineMessage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", uuid).
+			Order("id ASC").Find(&existing).Error; err != nil {
+			return err
+		}
+		expireAt := time.Now().Add(s.opts.Ttl)
+		rows := make([]OfflineMessage, 0, len(messages)+len(existing))
+		for _, message := range messages {
+			rows = append(rows, OfflineMessage{Uuid: uuid, Message: message, ExpireAt: expireAt})
+		}
+		for _, row := range existing {
+			rows = append(rows, OfflineMessage{Uuid: uuid, Message: row.Message, ExpireAt: row.ExpireAt, CreatedAt: row.CreatedAt})
+		}
+		if len(rows) > s.opts.Cap {
+			rows = rows[len(rows)-s.opts.Cap:]
+		}
+		if err := tx.Where("uuid = ?", uuid).Delete(&OfflineMessage{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+}
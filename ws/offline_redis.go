@@ -0,0 +1,105 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisOfflineStore 基于Redis列表的离线消息存储，每个uuid一个列表
+type redisOfflineStore struct {
+	rdb    redis.UniversalClient
+	prefix string
+	opts   OfflineOptions
+}
+
+type redisOfflineEntry struct {
+	ExpireAt int64  `json:"expire_at"`
+	Message  []byte `json:"message"`
+}
+
+// NewRedisOfflineStore 创建基于Redis的离线消息存储
+func NewRedisOfflineStore(rdb redis.UniversalClient, prefix string, opts OfflineOptions) OfflineStore {
+	return &redisOfflineStore{
+		rdb:    rdb,
+		prefix: prefix,
+		opts:   opts.withDefaults(),
+	}
+}
+
+func (s *redisOfflineStore) key(uuid string) string {
+	return s.prefix + ":offline:" + uuid
+}
+
+func (s *redisOfflineStore) Save(ctx context.Context, uuid string, message []byte) error {
+	data, err := json.Marshal(&redisOfflineEntry{ExpireAt: time.Now().Add(s.opts.Ttl).Unix(), Message: message})
+	if err != nil {
+		return err
+	}
+	key := s.key(uuid)
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, data)
+		pipe.LTrim(ctx, key, int64(-s.opts.Cap), -1)
+		pipe.Expire(ctx, key, s.opts.Ttl)
+		return nil
+	})
+	return err
+}
+
+// 一批全部过期时继续取下一批，直到取满或列表为空
+func (s *redisOfflineStore) Take(ctx context.Context, uuid string, limit int) ([][]byte, error) {
+	key := s.key(uuid)
+	var messages [][]byte
+	for len(messages) < limit {
+		n := limit - len(messages)
+		var lrange *redis.StringSliceCmd
+		_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			lrange = pipe.LRange(ctx, key, 0, int64(n-1))
+			pipe.LTrim(ctx, key, int64(n), -1)
+			return nil
+		})
+		if err != nil {
+			return messages, err
+		}
+		now := time.Now().Unix()
+		for _, item := range lrange.Val() {
+			var e redisOfflineEntry
+			if err := json.Unmarshal([]byte(item), &e); err != nil {
+				continue
+			}
+			if e.ExpireAt > now {
+				messages = append(messages, e.Message)
+			}
+		}
+		if len(lrange.Val()) < n {
+			break
+		}
+	}
+	return messages, nil
+}
+
+func (s *redisOfflineStore) Restore(ctx context.Context, uuid string, messages [][]byte) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	expireAt := time.Now().Add(s.opts.Ttl).Unix()
+	// LPUSH 逐个插入到头部，逆序插入后保持原顺序
+	values := make([]any, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		data, err := json.Marshal(&redisOfflineEntry{ExpireAt: expireAt, Message: messages[i]})
+		if err != nil {
+			return err
+		}
+		values = append(values, data)
+	}
+	key := s.key(uuid)
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, values...)
+		pipe.LTrim(ctx, key, int64(-s.opts.Cap), -1)
+		pipe.Expire(ctx, key, s.opts.Ttl)
+		return nil
+	})
+	return err
+}
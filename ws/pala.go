@@ -0,0 +1,108 @@
+package ws
+
+import (
+	"context"
+
+	"github.com/succko/hera/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Pala 服务的响应码
+const (
+	PalaCodeDelivered int32 = 0 // 已投递给在线客户端、房间或广播
+	PalaCodeStored    int32 = 1 // 目标不在线，已保存为离线消息
+	PalaCodeOffline   int32 = 2 // 目标不在线，消息被丢弃
+	PalaCodeFailed    int32 = 3 // 投递失败，例如房间成员查询失败
+)
+
+// 状态报告订阅的缓冲大小
+const reportBuffer = 256
+
+// PalaServer 将服务端通过gRPC下发的指令投递给websocket客户端。
+type PalaServer struct {
+	pb.UnimplementedPalaServer
+}
+
+// NewPalaServer 创建Pala服务。
+func NewPalaServer() *PalaServer {
+	return &PalaServer{}
+}
+
+// Send 投递一条指令，toId为ALL时广播给指令所属租户。
+func (s *PalaServer) Send(ctx context.Context, innoPacket *pb.InnoPacket) (*pb.Response, error) {
+	if err := validate(innoPacket); err != nil {
+		return nil, err
+	}
+	return response(innoPacket, HandleS2C(innoPacket)), nil
+}
+
+// SendBatch 批量投递指令，每条指令的投递结果按顺序流式返回。
+func (s *PalaServer) SendBatch(req *pb.BatchRequest, stream pb.Pala_SendBatchServer) error {
+	for _, innoPacket := range req.GetPackets() {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		var resp *pb.Response
+		if err := validate(innoPacket); err != nil {
+			resp = &pb.Response{Code: PalaCodeFailed, Message: status.Convert(err).Message(), RequestId: innoPacket.GetInstruction().GetRequestId()}
+		} else {
+			resp = response(innoPacket, HandleS2C(innoPacket))
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reports 流式返回租户内服务端指令的状态报告，直到客户端取消或Hub关闭。
+func (s *PalaServer) Reports(req *pb.ReportRequest, stream pb.Pala_ReportsServer) error {
+	h := SingletonHub()
+	reports, cancel := h.SubscribeReports(req.GetTenantId(), reportBuffer)
+	defer cancel()
+	zap.L().Info("pala reports subscribed", zap.Int64("tenantId", req.GetTenantId()))
+	for {
+		select {
+		case report := <-reports:
+			if err := stream.Send(report); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-h.quit:
+			return status.Error(codes.Unavailable, "ws hub stopped")
+		}
+	}
+}
+
+// 校验指令包，仅支持指定了目标或房间的指令
+func validate(innoPacket *pb.InnoPacket) error {
+	instruction := innoPacket.GetInstruction()
+	if innoPacket.GetType() != pb.InnoPacket_TYPE_INSTRUCTION || instruction == nil {
+		return status.Error(codes.InvalidArgument, "instruction packet required")
+	}
+	if instruction.GetToId() == "" && instruction.GetRoomId() == "" {
+		return status.Error(codes.InvalidArgument, "toId or roomId required")
+	}
+	return nil
+}
+
+// 将投递结果转换为响应
+func response(innoPacket *pb.InnoPacket, d Delivery) *pb.Response {
+	resp := &pb.Response{Message: d.String(), RequestId: innoPacket.GetInstruction().GetRequestId()}
+	switch d {
+	case DeliveryOnline, DeliveryBroadcast, DeliveryRoom:
+		resp.Code = PalaCodeDelivered
+	case DeliveryStored:
+		resp.Code = PalaCodeStored
+	case DeliveryOffline:
+		resp.Code = PalaCodeOffline
+		resp.Message = "target offline"
+	default:
+		resp.Code = PalaCodeFailed
+		resp.Message = "delivery failed"
+	}
+	return resp
+}
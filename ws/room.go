@@ -0,0 +1,194 @@
+package ws
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/protobuf/proto"
+	"github.com/succko/hera/pb"
+	"go.uber.org/zap"
+)
+
+// 租户内的唯一标识。默认租户0同样带前缀，避免 id 中的冒号与其他租户的键冲突
+func scopedKey(tenantId int64, id string) string {
+	return strconv.FormatInt(tenantId, 10) + ":" + id
+}
+
+// roomStore 房间成员存储，成员为租户内的uuid。成员关系在客户端断开后保留，直到主动离开
+type roomStore interface {
+	add(ctx context.Context, room, uuid string) error
+	remove(ctx context.Context, room, uuid string) error
+	members(ctx context.Context, room string) ([]string, error)
+	isMember(ctx context.Context, room, uuid string) (bool, error)
+}
+
+// Join 将uuid加入租户内的房间。
+func (h *Hub) Join(tenantId int64, roomId, uuid string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	return h.rooms.add(ctx, scopedKey(tenantId, roomId), uuid)
+}
+
+// Leave 将uuid移出租户内的房间。
+func (h *Hub) Leave(tenantId int64, roomId, uuid string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	return h.rooms.remove(ctx, scopedKey(tenantId, roomId), uuid)
+}
+
+// Members 获取租户内房间的所有成员uuid。
+func (h *Hub) Members(tenantId int64, roomId string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	return h.rooms.members(ctx, scopedKey(tenantId, roomId))
+}
+
+// SendToRoom 投递指令给租户内房间的所有成员，返回在线送达的成员数量。
+// 不在线的成员按offlineSend保存离线消息。
+func (h *Hub) SendToRoom(tenantId int64, roomId string, innoPacket *pb.InnoPacket) (int, error) {
+	return h.sendToRoom(tenantId, roomId, innoPacket, "", true)
+}
+
+// 投递指令给房间成员，except为发送方uuid，不会投递给发送方自己
+func (h *Hub) sendToRoom(tenantId int64, roomId string, innoPacket *pb.InnoPacket, except string, backend bool) (int, error) {
+	members, err := h.Members(tenantId, roomId)
+	if err != nil {
+		return 0, err
+	}
+	if instruction := innoPacket.GetInstruction(); instruction != nil {
+		instruction.TenantId = tenantId
+		instruction.RoomId = roomId
+	}
+	delivered := 0
+	for _, uuid := range members {
+		if uuid == except {
+			continue
+		}
+		// 每个成员的确认与状态报告独立跟踪，复制指令并改写目标
+		p := proto.Clone(innoPacket).(*pb.InnoPacket)
+		if instruction := p.GetInstruction(); instruction != nil {
+			instruction.ToId = uuid
+		}
+		if deliver(uuid, p, backend) == DeliveryOnline {
+			delivered++
+		}
+	}
+	zap.L().Info("sendToRoom", zap.Int64("tenantId", tenantId), zap.String("roomId", roomId), zap.Int("members", len(members)), zap.Int("delivered", delivered))
+	return delivered, nil
+}
+
+// 处理客户端的房间操作
+func (c *Client) handleRoom(room *pb.RoomPacket) {
+	if room.GetRoomId() == "" {
+		return
+	}
+	var err error
+	switch room.GetAction() {
+	case pb.RoomPacket_ACTION_JOIN:
+		err = c.hub.Join(c.tenantId, room.GetRoomId(), c.uuid)
+	case pb.RoomPacket_ACTION_LEAVE:
+		err = c.hub.Leave(c.tenantId, room.GetRoomId(), c.uuid)
+	case pb.RoomPacket_ACTION_MEMBERS:
+		var members []string
+		if members, err = c.hub.Members(c.tenantId, room.GetRoomId()); err == nil {
+			message, _ := proto.Marshal(&pb.InnoPacket{
+				Type: pb.InnoPacket_TYPE_ROOM,
+				Data: &pb.InnoPacket_Room{Room: &pb.RoomPacket{Action: room.GetAction(), RoomId: room.GetRoomId(), Members: members}},
+			})
+			c.trySend(message)
+		}
+	}
+	if err != nil {
+		zap.L().Error("handleRoom error", zap.String("uuid", c.uuid), zap.String("roomId", room.GetRoomId()), zap.Error(err))
+	}
+}
+
+// 客户端发送指令给所在的房间，发送方必须是房间成员
+func (c *Client) sendToRoom(innoPacket *pb.InnoPacket) {
+	roomId := innoPacket.GetInstruction().GetRoomId()
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	member, err := c.hub.rooms.isMember(ctx, scopedKey(c.tenantId, roomId), c.uuid)
+	cancel()
+	if err != nil || !member {
+		zap.L().Error("handleC2S sender not in room, message:"+innoPacket.String(), zap.String("uuid", c.uuid), zap.Error(err))
+		return
+	}
+	if _, err := c.hub.sendToRoom(c.tenantId, roomId, innoPacket, c.uuid, false); err != nil {
+		zap.L().Error("handleC2S sendToRoom error", zap.String("uuid", c.uuid), zap.String("roomId", roomId), zap.Error(err))
+	}
+}
+
+// memoryRoomStore 进程内的房间成员存储
+type memoryRoomStore struct {
+	mu    sync.RWMutex
+	rooms map[string]map[string]struct{}
+}
+
+func newMemoryRoomStore() *memoryRoomStore {
+	return &memoryRoomStore{rooms: make(map[string]map[string]struct{})}
+}
+
+func (s *memoryRoomStore) add(ctx context.Context, room, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rooms[room] == nil {
+		s.rooms[room] = make(map[string]struct{})
+	}
+	s.rooms[room][uuid] = struct{}{}
+	return nil
+}
+
+func (s *memoryRoomStore) remove(ctx context.Context, room, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rooms[room], uuid)
+	if len(s.rooms[room]) == 0 {
+		delete(s.rooms, room)
+	}
+	return nil
+}
+
+func (s *memoryRoomStore) members(ctx context.Context, room string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	members := make([]string, 0, len(s.rooms[room]))
+	for uuid := range s.rooms[room] {
+		members = append(members, uuid)
+	}
+	return members, nil
+}
+
+func (s *memoryRoomStore) isMember(ctx context.Context, room, uuid string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.rooms[room][uuid]
+	return ok, nil
+}
+
+// redisRoomStore 基于Redis集合的房间成员存储，集群模式下各节点共享
+type redisRoomStore struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+func (s *redisRoomStore) key(room string) string {
+	return s.prefix + ":room:" + room
+}
+
+func (s *redisRoomStore) add(ctx context.Context, room, uuid string) error {
+	return s.rdb.SAdd(ctx, s.key(room), uuid).Err()
+}
+
+func (s *redisRoomStore) remove(ctx context.Context, room, uuid string) error {
+	return s.rdb.SRem(ctx, s.key(room), uuid).Err()
+}
+
+func (s *redisRoomStore) members(ctx context.Context, room string) ([]string, error) {
+	return s.rdb.SMembers(ctx, s.key(room)).Result()
+}
+
+func (s *redisRoomStore) isMember(ctx context.Context, room, uuid string) (bool, error) {
+	return s.rdb.SIsMember(ctx, s.key(room), uuid).Result()
+}